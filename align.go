@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// Alignments of the table columns.
+const (
+	// alignAuto right-aligns the numeric columns such as value and epoch so that magnitudes can be compared at a glance.
+	alignAuto = "auto"
+	alignLeft = "left"
+)
+
+// columnAlignments returns the alignment of each column, which is right for the columns having only numbers.
+func columnAlignments(table *promql.Table) []int {
+	alignments := make([]int, len(table.Header))
+	for i := range alignments {
+		alignments[i] = tablewriter.ALIGN_LEFT
+		if isNumericColumn(table, i) {
+			alignments[i] = tablewriter.ALIGN_RIGHT
+		}
+	}
+	return alignments
+}
+
+// isNumericColumn returns true if all the non-empty values in the column are numbers, and there is at least one.
+func isNumericColumn(table *promql.Table, column int) bool {
+	numeric := false
+	for _, row := range table.Rows {
+		if column >= len(row.Columns) || row.Columns[column] == "" {
+			continue
+		}
+		if _, ok := parseSampleValue(row.Columns[column]); !ok {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// treeNode is a node of the tree shown by `\analyze`.
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+// runAnalyzeCommand runs `\analyze <query>`, which shows the execution breakdown of the query as a tree.
+// Servers other than Thanos show only the structure of the query parsed locally.
+func (c *CLI) runAnalyzeCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \analyze <query>`)
+	}
+	if c.remoteRead {
+		return errors.New(`\analyze is not supported with the remote read`)
+	}
+
+	opts := c.queryOptions()
+	opts.Analyze = true
+	stop := c.PrintProgressingMark()
+	resp, err := c.client.QueryContext(ctx, args, opts)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	if resp.Data.Analysis != nil {
+		writeTree(c.out, []*treeNode{analysisTree(resp.Data.Analysis)})
+		fmt.Fprintln(c.out)
+		return nil
+	}
+
+	nodes, err := queryStructure(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "The server doesn't support the query analysis, which needs Thanos with the Thanos engine.")
+	fmt.Fprintln(c.out, "The structure of the query is shown instead.")
+	writeTree(c.out, nodes)
+	fmt.Fprintln(c.out)
+	return nil
+}
+
+// analysisTree converts the analysis to the tree annotated with the execution time and the samples of each operator.
+func analysisTree(analysis *promql.QueryAnalysis) *treeNode {
+	node := &treeNode{label: fmt.Sprintf("%s (%s, %d samples, peak %d)",
+		analysis.Name, analysis.ExecutionTime, analysis.TotalSamples, analysis.PeakSamples)}
+	for i := range analysis.Children {
+		node.children = append(node.children, analysisTree(&analysis.Children[i]))
+	}
+	return node
+}
+
+// writeTree writes the nodes and their descendants, with the children indented by the tree lines.
+func writeTree(w io.Writer, nodes []*treeNode) {
+	for _, node := range nodes {
+		fmt.Fprintln(w, node.label)
+		writeSubtree(w, node.children, "")
+	}
+}
+
+func writeSubtree(w io.Writer, nodes []*treeNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├─ ", "│  "
+		if i == len(nodes)-1 {
+			branch, indent = "└─ ", "   "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, node.label)
+		writeSubtree(w, node.children, prefix+indent)
+	}
+}
+
+// queryStructure returns the top level nodes of the functions, the aggregations and the selectors in the query.
+func queryStructure(query string) ([]*treeNode, error) {
+	var tokens []token
+	all, err := lexPromQL(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		if t.typ != tokenComment {
+			tokens = append(tokens, t)
+		}
+	}
+
+	root := &treeNode{}
+	// stack is the node for each open parenthesis. Grouping parentheses push their parent again.
+	stack := []*treeNode{root}
+	var pending, closed *treeNode
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		parent := stack[len(stack)-1]
+		switch t.typ {
+		case tokenIdentifier:
+			next := tokens[i+1]
+			isAggregation := next.typ == tokenKeyword && groupingKeywords[strings.ToLower(next.val)]
+			if next.typ != tokenLeftParen && !isAggregation {
+				end := selectorEnd(tokens, i)
+				parent.children = append(parent.children, &treeNode{label: query[t.pos:end]})
+				i = tokenIndexAt(tokens, end) - 1
+				continue
+			}
+			pending = &treeNode{label: t.val}
+			parent.children = append(parent.children, pending)
+			if isAggregation {
+				pending.label += " " + labelList(query, tokens, i+1)
+				i = skipLabelList(tokens, i+1)
+			} else {
+				pending.label += "()"
+			}
+		case tokenLeftBrace:
+			end := selectorEnd(tokens, i)
+			parent.children = append(parent.children, &treeNode{label: query[t.pos:end]})
+			i = tokenIndexAt(tokens, end) - 1
+		case tokenKeyword:
+			// The grouping after the aggregation such as `sum(x) by (job)`, or the one of a binary operation.
+			if !groupingKeywords[strings.ToLower(t.val)] {
+				continue
+			}
+			if closed != nil && tokens[i-1].typ == tokenRightParen {
+				closed.label = strings.TrimSuffix(closed.label, "()") + " " + labelList(query, tokens, i)
+			}
+			i = skipLabelList(tokens, i)
+		case tokenLeftParen:
+			if pending != nil {
+				stack = append(stack, pending)
+				pending = nil
+			} else {
+				stack = append(stack, parent)
+			}
+		case tokenRightParen:
+			if len(stack) > 1 {
+				closed = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case tokenLeftBracket:
+			// The range of the subquery such as `rate(x[5m])[1h:1m]`.
+			j := i
+			for j < len(tokens)-1 && tokens[j].typ != tokenRightBracket {
+				j++
+			}
+			if closed != nil && tokens[i-1].typ == tokenRightParen {
+				closed.label += query[t.pos : tokens[j].pos+len(tokens[j].val)]
+			}
+			i = j
+		}
+	}
+	return root.children, nil
+}
+
+// selectorEnd returns the byte offset of the end of the selector starting at the token, including the matchers
+// and the range such as `up{job="a"}[5m]`.
+func selectorEnd(tokens []token, i int) int {
+	end := tokens[i].pos + len(tokens[i].val)
+	if tokens[i].typ == tokenIdentifier && tokens[i+1].typ == tokenLeftBrace {
+		i++
+	}
+	if tokens[i].typ == tokenLeftBrace {
+		for i < len(tokens)-1 && tokens[i].typ != tokenRightBrace {
+			i++
+		}
+		end = tokens[i].pos + len(tokens[i].val)
+	}
+	if i+3 < len(tokens) && tokens[i+1].typ == tokenLeftBracket && tokens[i+2].typ == tokenDuration && tokens[i+3].typ == tokenRightBracket {
+		end = tokens[i+3].pos + len(tokens[i+3].val)
+	}
+	return end
+}
+
+// tokenIndexAt returns the index of the first token starting at or after the byte offset.
+func tokenIndexAt(tokens []token, pos int) int {
+	for i, t := range tokens {
+		if t.pos >= pos {
+			return i
+		}
+	}
+	return len(tokens) - 1
+}
+
+// labelList returns the grouping keyword at the index with the following list of label names, such as `by (job)`.
+func labelList(query string, tokens []token, i int) string {
+	end := skipLabelList(tokens, i)
+	return strings.Join(strings.Fields(query[tokens[i].pos:tokens[end].pos+len(tokens[end].val)]), " ")
+}
+
+// skipLabelList returns the index of the closing parenthesis of the label names following the grouping keyword
+// at the index, or the keyword itself if no list follows.
+func skipLabelList(tokens []token, i int) int {
+	if tokens[i+1].typ != tokenLeftParen {
+		return i
+	}
+	j := i + 1
+	for j < len(tokens)-1 && tokens[j].typ != tokenRightParen {
+		j++
+	}
+	return j
+}
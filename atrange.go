@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runAtRangeCommand runs `\at-range <t1,t2,...> <query>`, which evaluates the instant query at each of the times
+// and shows the values of each series side by side, one column per time.
+func (c *CLI) runAtRangeCommand(ctx context.Context, args string) error {
+	spec, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if spec == "" || query == "" {
+		return errors.New(`usage: \at-range <t1,t2,...> <query>`)
+	}
+	if c.remoteRead {
+		return errors.New(`\at-range is not supported with the remote read`)
+	}
+
+	names := strings.Split(spec, ",")
+	times := make([]time.Time, len(names))
+	for i, name := range names {
+		t, err := parseTime(name)
+		if err != nil {
+			return err
+		}
+		times[i] = t
+	}
+
+	stop := c.PrintProgressingMark()
+	columns := make([]map[string]string, len(times))
+	for i, t := range times {
+		opts := c.queryOptions()
+		opts.Time = t
+		resp, _, err := c.queryWithOptions(ctx, query, opts)
+		if err != nil {
+			stop()
+			return fmt.Errorf("at %s: %w", names[i], err)
+		}
+		values, err := seriesValues(resp)
+		if err != nil {
+			stop()
+			return fmt.Errorf("at %s: %w", names[i], err)
+		}
+		columns[i] = values
+	}
+	stop()
+
+	table := buildAtRangeTable(names, columns)
+	summary := fmt.Sprintf("%d series at %d times from %s to %s", len(table.Rows), len(times),
+		times[0].Format(time.RFC3339), times[len(times)-1].Format(time.RFC3339))
+	if len(table.Rows) == 0 {
+		summary = "Empty result"
+	}
+	c.printTable(table, summary)
+	return nil
+}
+
+// buildAtRangeTable builds the table which has a row for each series and a value column for each time.
+// The value is blank if the series is missing at the time.
+func buildAtRangeTable(names []string, columns []map[string]string) *promql.Table {
+	seen := make(map[string]bool)
+	var fingerprints []string
+	for _, values := range columns {
+		for fp := range values {
+			if !seen[fp] {
+				seen[fp] = true
+				fingerprints = append(fingerprints, fp)
+			}
+		}
+	}
+	sort.Strings(fingerprints)
+
+	table := promql.Table{Header: append([]string{"labels"}, names...)}
+	for _, fp := range fingerprints {
+		row := promql.Row{Columns: []string{fp}}
+		for _, values := range columns {
+			row.Columns = append(row.Columns, values[fp])
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return &table
+}
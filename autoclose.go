@@ -0,0 +1,54 @@
+package main
+
+// closingBrackets maps the opening brackets to the closing ones.
+var closingBrackets = map[rune]rune{'(': ')', '[': ']', '{': '}'}
+
+// autoCloseBrackets implements the readline listener which inserts the closing bracket when an opening one is typed.
+// Typing the closing bracket right before the inserted one just moves the cursor over it.
+func autoCloseBrackets(line []rune, pos int, key rune) ([]rune, int, bool) {
+	// The listener is called for any key, including the ones not inserting a character such as arrow keys.
+	if pos == 0 || pos > len(line) || line[pos-1] != key {
+		return nil, 0, false
+	}
+
+	if closing, ok := closingBrackets[key]; ok {
+		newLine := make([]rune, 0, len(line)+1)
+		newLine = append(newLine, line[:pos]...)
+		newLine = append(newLine, closing)
+		newLine = append(newLine, line[pos:]...)
+		return newLine, pos, true
+	}
+	if isClosingBracket(key) && pos < len(line) && line[pos] == key {
+		newLine := make([]rune, 0, len(line)-1)
+		newLine = append(newLine, line[:pos]...)
+		newLine = append(newLine, line[pos+1:]...)
+		return newLine, pos, true
+	}
+	return nil, 0, false
+}
+
+func isClosingBracket(r rune) bool {
+	return r == ')' || r == ']' || r == '}'
+}
+
+// matchingBracket returns the index of the opening bracket matching the closing one at the index,
+// or -1 if there is no matching one.
+func matchingBracket(line []rune, index int) int {
+	closing := line[index]
+	depth := 0
+	for i := index; i >= 0; i-- {
+		switch {
+		case isClosingBracket(line[i]):
+			depth++
+		case closingBrackets[line[i]] != 0:
+			depth--
+			if depth == 0 {
+				if closingBrackets[line[i]] != closing {
+					return -1
+				}
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RunBatch runs the queries read from the input, up to concurrency in parallel, and writes the outputs in order.
+// The exit code is the same as -query's, for the worst of the queries.
+func (c *CLI) RunBatch(ctx context.Context, concurrency int) int {
+	var queries []string
+	scanner := bufio.NewScanner(c.in)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		queries = append(queries, splitStatements(scanner.Text())...)
+	}
+	if err := scanner.Err(); err != nil {
+		return c.ExitOnError(err)
+	}
+
+	outputs := make([]batchOutput, len(queries))
+	failed := make([]bool, len(queries))
+	empty := make([]bool, len(queries))
+	done := make([]chan struct{}, len(queries))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var err error
+				empty[i], err = c.runBatchQuery(ctx, queries[i], &outputs[i])
+				failed[i] = err != nil
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range queries {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	// Errors take precedence over empty results for the exit code.
+	exitCode := exitCodeSuccess
+	for i := range queries {
+		<-done[i]
+		if err := outputs[i].flush(c.out, c.errOut); err != nil {
+			return c.ExitOnError(err)
+		}
+		switch {
+		case failed[i]:
+			exitCode = exitCodeError
+		case empty[i] && exitCode == exitCodeSuccess:
+			exitCode = exitCodeEmpty
+		}
+	}
+	wg.Wait()
+	return exitCode
+}
+
+// runBatchQuery runs the query as -query does, and records its output. True is returned for an empty result.
+func (c *CLI) runBatchQuery(ctx context.Context, query string, output *batchOutput) (bool, error) {
+	// The CLI is copied to record the output of each query separately.
+	worker := *c
+	worker.out, worker.errOut = output.writer(false), output.writer(true)
+
+	// The query isn't echoed for the formats processed by other tools.
+	if c.format == formatTable || c.format == formatExpanded || c.format == formatMarkdown {
+		fmt.Fprintf(worker.out, "%s\n", query)
+	}
+	empty, err := worker.runNonInteractiveQuery(ctx, query)
+	if err != nil {
+		fmt.Fprintf(worker.out, "ERROR: %s\n\n", err)
+		return false, err
+	}
+	if c.dryRun {
+		fmt.Fprintln(worker.out)
+	}
+	return empty, nil
+}
+
+// batchOutput records the output of a query to stdout and stderr in the order written.
+type batchOutput struct {
+	mu     sync.Mutex
+	chunks []batchChunk
+}
+
+type batchChunk struct {
+	stderr bool
+	b      []byte
+}
+
+// writer returns the writer recording to stderr if stderr is true, or to stdout otherwise.
+func (o *batchOutput) writer(stderr bool) io.Writer {
+	return batchOutputWriter{output: o, stderr: stderr}
+}
+
+// flush writes the recorded output to out and errOut.
+func (o *batchOutput) flush(out, errOut io.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, chunk := range o.chunks {
+		w := out
+		if chunk.stderr {
+			w = errOut
+		}
+		if _, err := w.Write(chunk.b); err != nil {
+			return err
+		}
+	}
+	o.chunks = nil
+	return nil
+}
+
+type batchOutputWriter struct {
+	output *batchOutput
+	stderr bool
+}
+
+func (w batchOutputWriter) Write(b []byte) (int, error) {
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+	w.output.chunks = append(w.output.chunks, batchChunk{stderr: w.stderr, b: bytes.Clone(b)})
+	return len(b), nil
+}
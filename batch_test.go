@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch_Stderr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":%q},"value":[1719292597.171,"1"]}]},"warnings":["partial result"]}`, r.FormValue("query"))
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	config := &Config{
+		URL:             server.URL,
+		Format:          formatCSV,
+		TimestampFormat: timestampFormatEpoch,
+		Align:           alignAuto,
+		MatrixLayout:    matrixLayoutPoint,
+		Timing:          timingModeSimple,
+		ShowType:        true,
+		NoSpinner:       true,
+	}
+	c, err := NewCLI(config, io.NopCloser(strings.NewReader("a\nb; c\nd\n")), &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := c.RunBatch(context.Background(), 3); code != exitCodeSuccess {
+		t.Fatalf("RunBatch() = %d, want %d, stderr: %s", code, exitCodeSuccess, stderr.String())
+	}
+
+	// Only the results are written to stdout, in the order of the input.
+	var want strings.Builder
+	for _, job := range []string{"a", "b", "c", "d"} {
+		fmt.Fprintf(&want, "epoch,job,value\n1719292597.171,%s,1\n", job)
+	}
+	if stdout.String() != want.String() {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want.String())
+	}
+	for _, s := range []string{"resultType: vector", "partial result"} {
+		if got := strings.Count(stderr.String(), s); got != 4 {
+			t.Errorf("stderr has %q %d times, want 4: %s", s, got, stderr.String())
+		}
+	}
+}
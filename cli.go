@@ -2,52 +2,347 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"os/signal"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/chzyer/readline"
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
+	"github.com/yfuruyama/promql-cli/pkg/promql"
 )
 
 const (
 	exitCodeSuccess = 0
 	exitCodeError   = 1
+	// exitCodeEmpty is returned for an empty result with -fail-on-empty, which is distinguished from errors.
+	exitCodeEmpty = 2
 
 	defaultPrompt = "promql> "
+
+	// progressElapsedThreshold is the elapsed time of a query after which the spinner also shows the elapsed time.
+	progressElapsedThreshold = 1 * time.Second
 )
 
 type CLI struct {
-	client *Client
-	in     io.ReadCloser
-	out    io.Writer
+	// client is the primary client. When multiple servers are given, queries are sent to all of clients.
+	client      *promql.Client
+	clients     []*promql.Client
+	in          io.ReadCloser
+	out         io.Writer
+	errOut      io.Writer
+	timing      string
+	dryRun      bool
+	showSecrets bool
+	format      string
+	noHeader    bool
+	noSpinner   bool
+	// formatBeforeExpanded is the format restored when the expanded display is turned off by `\x`.
+	formatBeforeExpanded string
+	// cardinalityWarn is the number of series above which running a bare selector needs confirmation.
+	cardinalityWarn int
+	// maxSamplesWarn is the estimated number of samples per series above which running a query needs confirmation.
+	maxSamplesWarn int
+	failFast       bool
+	// failOnEmpty makes RunOnce exit with exitCodeEmpty if the result is empty.
+	failOnEmpty bool
+	// valueOnly makes RunOnce print only the value of a single value result.
+	valueOnly bool
+	// showType shows the result type such as vector above the result.
+	showType bool
+	// saveSettings enables saving the session settings on exit.
+	saveSettings bool
+	// initialSettings are the settings at the start of the session, to save only the ones changed in the session.
+	initialSettings *Settings
+	// vars are the variables substituted in queries, which are defined by `\var`.
+	vars map[string]string
+	// pasting is true while reading the lines of a query by `\paste`.
+	pasting bool
+	// formatOverride is the format given for the query read by ReadInput, such as by `\json <query>`.
+	formatOverride string
+	// prettyJSON indents the JSON output. It's on by default only if the output is a terminal.
+	prettyJSON bool
+	// tui shows the table results in the scrollable browser instead of printing them, if both input and output are terminals.
+	tui bool
+	// align is either alignAuto or alignLeft.
+	align string
+	// autoClose enables inserting the closing bracket automatically in the interactive mode.
+	autoClose bool
+	// transportOpts are kept to create the clients for other servers, such as the one of `\metrics-diff`.
+	transportOpts promql.TransportOptions
+	// otherClients are the clients for other servers by the URL, which are reused across the commands.
+	otherClients map[string]*promql.Client
+	// remoteRead is true if queries are read by the remote read protocol from the client's URL.
+	remoteRead bool
+	// localDB is the TSDB opened for -tsdb-path, which is closed by Close.
+	localDB io.Closer
+	// originLabel is the label added to the series merged from multiple servers.
+	originLabel string
+
+	rl *readline.Instance
+	// historySearch is the state of the prefix search of the history by Up and Down.
+	historySearch historySearch
+
+	// lastQuery is the query most recently run in the interactive mode.
+	lastQuery string
+	// lastResponse is the response of the query most recently succeeded in the interactive mode.
+	lastResponse *promql.QueryResponse
+	// pinnedTime is the evaluation time for queries set by \pin. Queries are evaluated at the current time if zero.
+	pinnedTime time.Time
+	// lookbackDelta overrides the lookback delta of the server for queries if not empty.
+	lookbackDelta string
+	// serverTimeout bounds the evaluation of queries on the server if not empty, which is set by `\server-timeout`.
+	serverTimeout string
+
+	tableOptions TableOptions
+}
+
+// Config holds the options given by command line flags.
+type Config struct {
+	URL         string
+	Project     string
+	Headers     string
+	Timing      string
+	DryRun      bool
+	ShowSecrets bool
+	CacheTTL    time.Duration
+	MaxWidth    int
+	Format      string
+	Align       string
+	PrettyJSON  bool
+	NoHeader    bool
+	NoSpinner   bool
+	TUI         bool
+	BoolMarkers bool
+	DropLabels  string
+	// DurationMetrics renders the values of the metrics in seconds as durations.
+	DurationMetrics bool
+	// NoExponent renders the values in the scientific notation in plain decimals.
+	NoExponent bool
+	DedupBy    string
+	TrimPrefix string
+	Transport  promql.TransportOptions
+	GCP        promql.GCPOptions
+
+	MatrixLayout string
+	Summary      bool
+
+	TimestampFormat string
+
+	CardinalityWarn int
+	MaxSamplesWarn  int
+	FailFast        bool
+	FailOnEmpty     bool
+	ValueOnly       bool
+	ShowType        bool
+	// ServerTimeout is the timeout parameter of the queries, or empty to omit it.
+	ServerTimeout string
+
+	// Dedup and PartialResponse are "true" or "false" to be sent as the query parameters, or empty to omit them.
+	Dedup           string
+	PartialResponse string
+
+	// LookbackDelta, SaveSettings and Units are about the session settings persisted across sessions.
+	LookbackDelta string
+	SaveSettings  bool
+	Units         map[string]string
+
+	// OriginLabel is the label to tell the servers apart in the results merged from multiple servers.
+	OriginLabel string
+
+	// RemoteReadURL is the remote read endpoint, which is used instead of URL if given.
+	RemoteReadURL string
+
+	// TSDBPath is the TSDB directory to evaluate the queries on locally instead of querying the server if given.
+	TSDBPath string
+
+	// RecordDir is the directory to record the query responses to for -replay.
+	RecordDir string
 }
 
-func NewCLI(url, project, headers string, in io.ReadCloser, out io.Writer) (*CLI, error) {
+func NewCLI(config *Config, in io.ReadCloser, out io.Writer, errOut io.Writer) (*CLI, error) {
 	ctx := context.Background()
-	client, err := NewClient(ctx, url, project, headers)
-	if err != nil {
-		return nil, err
+
+	if !isValidFormat(config.Format) {
+		return nil, fmt.Errorf("unknown format: %q (available: %s)", config.Format, strings.Join(outputFormats, ", "))
+	}
+	if !isValidTimestampFormat(config.TimestampFormat) {
+		return nil, fmt.Errorf("unknown timestamp format: %q (available: %s)", config.TimestampFormat, strings.Join(timestampFormats, ", "))
+	}
+	if config.Align != alignAuto && config.Align != alignLeft {
+		return nil, fmt.Errorf("unknown alignment: %q (available: %s, %s)", config.Align, alignAuto, alignLeft)
+	}
+	if config.RecordDir != "" {
+		if err := os.MkdirAll(config.RecordDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create the record directory: %w", err)
+		}
+	}
+	if config.MatrixLayout != matrixLayoutPoint && config.MatrixLayout != matrixLayoutSeries {
+		return nil, fmt.Errorf("unknown matrix layout: %q (available: %s, %s)", config.MatrixLayout, matrixLayoutPoint, matrixLayoutSeries)
+	}
+
+	// Multiple servers can be given as comma separated URLs, except for Google Cloud Monitoring.
+	originLabel := config.OriginLabel
+	if originLabel == "" {
+		originLabel = defaultOriginLabel
+	}
+
+	urls := []string{config.URL}
+	if config.Project == "" {
+		urls = strings.Split(config.URL, ",")
+	}
+	if config.RemoteReadURL != "" {
+		if config.Project != "" {
+			return nil, errors.New("-remote-read-url cannot be used with -project")
+		}
+		urls = []string{config.RemoteReadURL}
+	}
+	var localDB io.Closer
+	var localTransport http.RoundTripper
+	if config.TSDBPath != "" {
+		if config.Project != "" || config.RemoteReadURL != "" {
+			return nil, errors.New("-tsdb-path cannot be used with -project or -remote-read-url")
+		}
+		var baseURL string
+		var err error
+		if baseURL, localTransport, localDB, err = openLocalTSDB(config.TSDBPath); err != nil {
+			return nil, err
+		}
+		urls = []string{baseURL}
+	}
+
+	var clients []*promql.Client
+	for _, url := range urls {
+		client, err := promql.NewClient(ctx, strings.TrimSpace(url), config.Project, config.Headers, config.Transport, config.GCP)
+		if err != nil {
+			return nil, err
+		}
+		slog.Debug("client created", "url", client.BaseURL(), "auth", client.AuthMode())
+		if config.CacheTTL > 0 {
+			client.EnableCache(config.CacheTTL)
+		}
+		if config.Dedup != "" {
+			client.SetQueryParam(dedupParam, config.Dedup)
+		}
+		if config.PartialResponse != "" {
+			client.SetQueryParam(partialResponseParam, config.PartialResponse)
+		}
+		if config.RecordDir != "" {
+			client.SetRecordDir(config.RecordDir)
+		}
+		if localTransport != nil {
+			client.SetHTTPClient(&http.Client{Transport: localTransport})
+		}
+		clients = append(clients, client)
 	}
 
+	// The spinner writes to the output concurrently with others such as error messages.
+	writers := newLockedWriters(out, errOut)
+	out, errOut = writers[0], writers[1]
+
 	return &CLI{
-		client: client,
-		in:     in,
-		out:    out,
+		client:      clients[0],
+		clients:     clients,
+		in:          in,
+		out:         out,
+		errOut:      errOut,
+		timing:      config.Timing,
+		dryRun:      config.DryRun,
+		showSecrets: config.ShowSecrets,
+		format:      config.Format,
+		noHeader:    config.NoHeader,
+		noSpinner:   config.NoSpinner,
+
+		cardinalityWarn: config.CardinalityWarn,
+		maxSamplesWarn:  config.MaxSamplesWarn,
+		failFast:        config.FailFast,
+		failOnEmpty:     config.FailOnEmpty,
+		valueOnly:       config.ValueOnly,
+		showType:        config.ShowType,
+
+		lookbackDelta: config.LookbackDelta,
+		serverTimeout: config.ServerTimeout,
+		saveSettings:  config.SaveSettings,
+		initialSettings: &Settings{
+			Format:        config.Format,
+			NoHeader:      config.NoHeader,
+			MaxWidth:      config.MaxWidth,
+			LookbackDelta: config.LookbackDelta,
+		},
+		remoteRead:    config.RemoteReadURL != "",
+		localDB:       localDB,
+		originLabel:   originLabel,
+		transportOpts: config.Transport,
+		autoClose:     true,
+		align:         config.Align,
+		prettyJSON:    config.PrettyJSON || isTerminal(out),
+		tui:           config.TUI,
+
+		tableOptions: TableOptions{
+			MaxWidth:    config.MaxWidth,
+			BoolMarkers: config.BoolMarkers,
+			DropLabels:  parseDropLabels(config.DropLabels),
+			DedupBy:     config.DedupBy,
+			TrimPrefix:  config.TrimPrefix,
+
+			DurationMetrics: config.DurationMetrics,
+			NoExponent:      config.NoExponent,
+			Units:           config.Units,
+
+			MatrixLayout: config.MatrixLayout,
+			Summary:      config.Summary,
+
+			TimestampFormat: config.TimestampFormat,
+		},
 	}, nil
 }
 
-func (c *CLI) RunInteractive() int {
-	rl, err := readline.NewEx(&readline.Config{
+func (c *CLI) RunInteractive(ctx context.Context) int {
+	rlConfig := &readline.Config{
 		Stdin:       c.in,
-		HistoryFile: "/tmp/promql_cli_history",
+		HistoryFile: historyFile,
+	}
+	if err := checkHistoryFile(rlConfig.HistoryFile); err != nil {
+		// The REPL is still usable without the history file, so the history is kept only in memory.
+		fmt.Fprintf(c.errOut, "WARNING: failed to open the history file, the history is not saved: %s\n", err)
+		rlConfig.HistoryFile = ""
+	}
+	// The pasted multi-line query is joined into one line instead of being run on the first newline.
+	// F5 cycles the output formats, which is translated before readline since it drops the function keys.
+	if f, ok := c.in.(*os.File); ok && readline.IsTerminal(int(f.Fd())) && isTerminal(c.out) {
+		rlConfig.Stdin = newFunctionKeyReader(newBracketedPasteReader(c.in))
+		rlConfig.FuncFilterInputRune = c.filterInputRune
+		fmt.Fprint(c.out, bracketedPasteOn)
+		defer fmt.Fprint(c.out, bracketedPasteOff)
+	}
+	if colorEnabled(c.out) {
+		rlConfig.Painter = &promqlPainter{}
+	}
+	rlConfig.SetListener(func(line []rune, pos int, key rune) ([]rune, int, bool) {
+		c.historySearch.line = string(line)
+		if !c.autoClose || c.pasting {
+			return nil, 0, false
+		}
+		newLine, newPos, ok := autoCloseBrackets(line, pos, key)
+		if ok {
+			c.historySearch.line = string(newLine)
+		}
+		return newLine, newPos, ok
 	})
+	rl, err := readline.NewEx(rlConfig)
 	if err != nil {
 		return c.ExitOnError(err)
 	}
-	rl.SetPrompt(defaultPrompt)
+	c.rl = rl
 
 	for {
 		input, err := c.ReadInput(rl)
@@ -62,150 +357,564 @@ func (c *CLI) RunInteractive() int {
 			return c.Exit()
 		}
 
-		stop := c.PrintProgressingMark()
-		resp, err := c.client.Query(input)
-		stop()
+		// Ctrl-C while the input is running cancels it and returns to the prompt, instead of exiting.
+		inputCtx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+		if isCommand(input) {
+			if err := c.runCommand(inputCtx, input); err != nil {
+				c.PrintInteractiveError(err)
+			}
+			cancel()
+			continue
+		}
+
+		// The format given for the query is used only for it, and the session default is restored afterwards.
+		defaultFormat := c.format
+		if c.formatOverride != "" {
+			c.format, c.formatOverride = c.formatOverride, ""
+		}
+		statements := splitStatements(input)
+		for i, statement := range statements {
+			if inputCtx.Err() != nil {
+				break
+			}
+			if len(statements) > 1 {
+				if i > 0 {
+					fmt.Fprintln(c.out, "----")
+				}
+				fmt.Fprintf(c.out, "%s\n", statement)
+			}
+			c.runQuery(inputCtx, statement)
+		}
+		cancel()
+		c.format = defaultFormat
+	}
+}
+
+// runQuery runs the query in the interactive mode and prints the result.
+func (c *CLI) runQuery(ctx context.Context, input string) {
+	input, err := expandVariables(input, c.vars)
+	if err != nil {
+		c.PrintInteractiveError(err)
+		return
+	}
+	c.lastQuery = input
+
+	if c.dryRun {
+		if err := c.PrintDryRun(input); err != nil {
+			c.PrintInteractiveError(err)
+		}
+		return
+	}
+
+	if warning := c.queryWarning(ctx, input); warning != "" {
+		if !c.confirm(warning + " Run anyway? [y/N] ") {
+			fmt.Fprintf(c.out, "Canceled\n\n")
+			return
+		}
+	}
+
+	stop := c.PrintProgressingMark()
+	resp, timing, err := c.query(ctx, input)
+	stop()
+	if err != nil {
+		c.PrintInteractiveError(err)
+		return
+	}
+
+	c.lastResponse = resp
+	c.PrintResult(resp, c.formatTiming(timing))
+}
+
+// RunOnce runs the given query only once and exits, which is useful for scripting.
+func (c *CLI) RunOnce(ctx context.Context, query string) int {
+	empty, err := c.runNonInteractiveQuery(ctx, query)
+	if err != nil {
+		return c.ExitOnError(err)
+	}
+	if empty {
+		return exitCodeEmpty
+	}
+	return exitCodeSuccess
+}
+
+// runNonInteractiveQuery runs the query for -query and -batch, and prints the result. True is returned if the result
+// is empty with -fail-on-empty, which is told by the exit code.
+func (c *CLI) runNonInteractiveQuery(ctx context.Context, query string) (bool, error) {
+	query, err := expandVariables(query, c.vars)
+	if err != nil {
+		return false, err
+	}
+	if c.dryRun {
+		return false, c.PrintDryRun(query)
+	}
+
+	// There is no way to confirm in the non-interactive mode, so the query is refused only with -fail-fast.
+	if warning := c.queryWarning(ctx, query); warning != "" {
+		if c.failFast {
+			return false, errors.New(warning)
+		}
+		fmt.Fprintf(c.errOut, "WARNING: %s\n", warning)
+	}
+
+	resp, timing, err := c.query(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	empty := c.failOnEmpty && isEmptyResult(resp)
+	switch {
+	case !c.valueOnly:
+		c.PrintResult(resp, "")
+	case empty:
+		// Nothing is printed, and the exit code tells the result is empty.
+	default:
+		value, err := singleValue(resp)
 		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(c.out, value)
+		c.PrintAnnotations(resp)
+	}
+
+	// Timing goes to stderr so that it doesn't pollute the piped output.
+	if c.timing != timingModeOff {
+		fmt.Fprintln(c.errOut, timing.Format(c.timing == timingModeDetailed))
+	}
+	return empty, nil
+}
+
+// queryOptions returns the query parameters set for the session.
+func (c *CLI) queryOptions() promql.QueryOptions {
+	return promql.QueryOptions{
+		Time:          c.pinnedTime,
+		LookbackDelta: c.lookbackDelta,
+		Timeout:       c.serverTimeout,
+	}
+}
+
+func (c *CLI) query(ctx context.Context, q string) (*promql.QueryResponse, *QueryTiming, error) {
+	return c.queryWithOptions(ctx, q, c.queryOptions())
+}
+
+// queryWithOptions runs the query with the parameters other than the session ones, such as another evaluation time.
+// The options are ignored by the remote read.
+func (c *CLI) queryWithOptions(ctx context.Context, q string, opts promql.QueryOptions) (*promql.QueryResponse, *QueryTiming, error) {
+	timing := newQueryTiming()
+	if c.remoteRead {
+		resp, err := c.remoteReadQuery(ctx, q)
+		timing.Stop()
+		return resp, timing, err
+	}
+	if len(c.clients) > 1 {
+		resp, serverErrs, err := c.queryAll(ctx, q, opts)
+		timing.Stop()
+		for origin, serverErr := range serverErrs {
+			fmt.Fprintf(c.errOut, "WARNING: query failed on %s: %s\n", origin, serverErr)
+		}
+		return resp, timing, err
+	}
+
+	var trace *httptrace.ClientTrace
+	if c.timing == timingModeDetailed {
+		trace = timing.ClientTrace()
+	}
+	opts.Trace = trace
+	resp, err := c.client.QueryContext(ctx, q, opts)
+	timing.Stop()
+	if resp != nil {
+		timing.RequestID = resp.RequestID
+	}
+	return resp, timing, err
+}
+
+// PrintResult renders the result in the output format. The note, such as timing, is shown after the result.
+func (c *CLI) PrintResult(resp *promql.QueryResponse, note string) {
+	if resp.Cached {
+		note = " (cached)" + note
+	}
+
+	if c.format == formatRawJSON {
+		if err := promql.WriteRawJSON(c.out, resp, c.prettyJSON); err != nil {
 			c.PrintInteractiveError(err)
-			continue
 		}
+		if note != "" {
+			fmt.Fprintln(c.errOut, strings.TrimSpace(note))
+		}
+		c.PrintAnnotations(resp)
+		return
+	}
 
-		table := buildTable(resp)
+	resp, collapsed := dedupResponse(resp, c.tableOptions.DedupBy)
+	if collapsed > 0 {
+		note = fmt.Sprintf(" (%d duplicate series collapsed)", collapsed) + note
+	}
+	table := buildTable(resp, &c.tableOptions)
+	c.renameColumns(table)
+
+	// For formats other than table, nothing but the values is written to the output so that it can be processed by other tools.
+	switch c.format {
+	case formatCSV, formatTSV, formatMarkdown:
+		if c.showType {
+			fmt.Fprintf(c.errOut, "resultType: %s\n", resp.Data.ResultType)
+		}
+		write := tableWriters[c.format]
 		if len(table.Rows) > 0 {
-			w := tablewriter.NewWriter(c.out)
-			w.SetAutoFormatHeaders(false)
-			w.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-			w.SetAlignment(tablewriter.ALIGN_LEFT)
-			w.SetAutoWrapText(false)
-			for _, row := range table.Rows {
-				w.Append(row.Columns)
+			if err := write(c.out, table, c.noHeader); err != nil {
+				c.PrintInteractiveError(err)
+			}
+		}
+		if note != "" {
+			fmt.Fprintln(c.errOut, strings.TrimSpace(note))
+		}
+		c.PrintAnnotations(resp)
+		return
+	}
+
+	if c.showType {
+		fmt.Fprintf(c.out, "resultType: %s\n", resp.Data.ResultType)
+	}
+	if len(table.Rows) > 0 {
+		if c.format == formatExpanded {
+			if err := promql.WriteExpanded(c.out, table); err != nil {
+				c.PrintInteractiveError(err)
 			}
-			w.SetHeader(table.Header)
-			w.Render()
-			fmt.Fprintf(c.out, "%d values in result\n\n", len(table.Rows))
 		} else {
-			fmt.Fprintf(c.out, "Empty result\n\n")
+			c.renderTable(table)
+		}
+		fmt.Fprintf(c.out, "%d values in result%s\n\n", len(table.Rows), note)
+	} else {
+		fmt.Fprintf(c.out, "Empty result%s\n\n", note)
+	}
+	c.PrintAnnotations(resp)
+}
+
+func (c *CLI) renderTable(table *promql.Table) {
+	if c.tui {
+		if in, out, ok := c.browserTerminal(); ok {
+			if err := c.browseTable(table, in, out); err != nil {
+				c.PrintInteractiveError(err)
+			}
+			return
+		}
+	}
+	c.writeTable(c.out, table)
+}
+
+func (c *CLI) writeTable(out io.Writer, table *promql.Table) {
+	w := tablewriter.NewWriter(out)
+	w.SetAutoFormatHeaders(false)
+	w.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	w.SetAlignment(tablewriter.ALIGN_LEFT)
+	w.SetAutoWrapText(false)
+	if c.align == alignAuto {
+		w.SetColumnAlignment(columnAlignments(table))
+	}
+	for _, row := range table.Rows {
+		w.Append(row.Columns)
+	}
+	if !c.noHeader {
+		w.SetHeader(table.Header)
+	}
+	w.Render()
+}
+
+// isEmptyResult returns true if the vector or the matrix result has no series. Scalars and strings are never empty.
+func isEmptyResult(resp *promql.QueryResponse) bool {
+	switch result := resp.Data.Result.(type) {
+	case promql.ResultVector:
+		return len(result) == 0
+	case promql.ResultMatrix:
+		return len(result) == 0
+	}
+	return false
+}
+
+// PrintAnnotations prints the warnings (e.g. for partial results) and infos returned by the server to stderr.
+func (c *CLI) PrintAnnotations(resp *promql.QueryResponse) {
+	for _, warning := range resp.Warnings {
+		c.printAnnotation("WARNING", warning, colorYellow)
+	}
+	for _, info := range resp.Infos {
+		c.printAnnotation("INFO", info, colorCyan)
+	}
+}
+
+func (c *CLI) printAnnotation(level, msg, color string) {
+	msg = fmt.Sprintf("%s: %s", level, msg)
+	if colorEnabled(c.errOut) {
+		msg = colorize(msg, color)
+	}
+	fmt.Fprintln(c.errOut, msg)
+}
+
+// PrintDryRun prints the request URL and the equivalent curl command instead of sending the query.
+func (c *CLI) PrintDryRun(q string) error {
+	for _, client := range c.clients {
+		req, err := client.NewQueryRequest(q, c.queryOptions())
+		if err != nil {
+			return err
 		}
+		fmt.Fprintln(c.out, req.URL.String())
+		fmt.Fprintln(c.out, client.CurlCommand(req, c.showSecrets))
 	}
+	return nil
+}
+
+func (c *CLI) formatTiming(timing *QueryTiming) string {
+	if c.timing == timingModeOff {
+		return ""
+	}
+	return " " + timing.Format(c.timing == timingModeDetailed)
 }
 
 func (c *CLI) ReadInput(rl *readline.Instance) (string, error) {
-	defer rl.SetPrompt(defaultPrompt)
+	rl.SetPrompt(c.prompt())
 
+	// buffer is the initial content of the line, which is used to restore the input when the editor failed.
+	var buffer string
 	for {
-		line, err := rl.Readline()
+		line, err := rl.ReadlineWithDefault(buffer)
 		if err != nil {
 			return "", err
 		}
+		buffer = ""
 		if line == "" {
 			continue
 		}
+		line = strings.TrimSpace(line)
 
-		return strings.TrimSpace(line), nil
+		// `\edit [query]` opens the query, or the last query if omitted, in the editor and runs the saved content.
+		if line == `\edit` || strings.HasPrefix(line, `\edit `) {
+			original := strings.TrimSpace(strings.TrimPrefix(line, `\edit`))
+			if original == "" {
+				original = c.lastQuery
+			}
+			edited, err := editInEditor(original)
+			if err != nil {
+				c.PrintInteractiveError(err)
+				buffer = original
+				continue
+			}
+			rl.SaveHistory(edited)
+			return edited, nil
+		}
+
+		if line == `\paste` {
+			query, err := c.readPaste(rl)
+			if err != nil {
+				return "", err
+			}
+			if query == "" {
+				continue
+			}
+			return query, nil
+		}
+
+		query, format, err := parseFormatOverride(line)
+		if err != nil {
+			c.PrintInteractiveError(err)
+			continue
+		}
+		c.formatOverride = format
+		return query, nil
+	}
+}
+
+// prompt returns the prompt, which shows the pinned evaluation time if set.
+func (c *CLI) prompt() string {
+	if c.pinnedTime.IsZero() {
+		return defaultPrompt
 	}
+	return fmt.Sprintf("promql@%s> ", c.pinnedTime.Format(time.RFC3339))
 }
 
 func (c *CLI) Exit() int {
+	if c.saveSettings {
+		saved, err := loadSettings()
+		if err != nil || saved == nil {
+			saved = &Settings{}
+		}
+		if err := saveSettings(c.changedSettings(saved)); err != nil {
+			fmt.Fprintf(c.errOut, "WARNING: failed to save the settings: %s\n", err)
+		}
+	}
 	fmt.Fprintln(c.out, "Bye")
 	return exitCodeSuccess
 }
 
+// Close releases the resources held through the session, such as the TSDB opened for -tsdb-path.
+func (c *CLI) Close() error {
+	if c.localDB != nil {
+		return c.localDB.Close()
+	}
+	return nil
+}
+
 func (c *CLI) ExitOnError(err error) int {
 	fmt.Fprintf(c.out, "ERROR: %s\n", err)
 	return exitCodeError
 }
 
 func (c *CLI) PrintInteractiveError(err error) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(c.out, "Canceled\n\n")
+		return
+	}
 	fmt.Fprintf(c.out, "ERROR: %s\n", err)
 }
 
+// PrintProgressingMark shows the spinner with the elapsed time until the returned function is called.
+// Nothing is shown if the output is not a terminal.
 func (c *CLI) PrintProgressingMark() func() {
+	if c.noSpinner || !isTerminal(c.out) {
+		return func() {}
+	}
+
 	progressMarks := []string{`-`, `\`, `|`, `/`}
+	start := time.Now()
 	ticker := time.NewTicker(time.Millisecond * 100)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		i := 0
 		for {
-			<-ticker.C
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
 			mark := progressMarks[i%len(progressMarks)]
+			if elapsed := time.Since(start); elapsed >= progressElapsedThreshold {
+				mark = fmt.Sprintf("%s %ds", mark, int(elapsed.Seconds()))
+			}
 			fmt.Fprintf(c.out, "\r%s", mark)
 			i++
 		}
 	}()
 
+	// stop waits for the spinner goroutine to exit, so that nothing is written by the spinner after it returns.
 	stop := func() {
 		ticker.Stop()
-		fmt.Fprintf(c.out, "\r") // clear progressing mark
+		close(done)
+		<-stopped
+		fmt.Fprintf(c.out, "\r\033[K") // clear progressing mark
 	}
 	return stop
 }
 
-type Table struct {
-	Header []string
-	Rows   []Row
+// TableOptions controls how the result is rendered as a table without changing the result itself.
+type TableOptions struct {
+	RenameRules []labelRenameRule
+	// ColumnNames are the names shown in the header instead of the label names, which are set by `\rename-column`.
+	ColumnNames map[string]string
+	// MaxWidth is the maximum display width of a label value, or zero for no truncation.
+	MaxWidth int
+	// DropLabels are the labels hidden from the output. The result itself retains them.
+	DropLabels []string
+	// TrimPrefix is the common prefix stripped from the metric names in the output. The result itself retains it.
+	TrimPrefix string
+	// DedupBy is the label differentiating the duplicate series of a vector result, such as an external label
+	// added by federation. The series with the same labels except it are collapsed into the most recent one.
+	DedupBy string
+	// MatrixLayout is either matrixLayoutPoint or matrixLayoutSeries.
+	MatrixLayout string
+	// TimestampFormat is either timestampFormatRFC3339, timestampFormatEpoch or timestampFormatBoth.
+	TimestampFormat string
+	// Summary renders a range vector result as one row per series with min, avg, max and last values.
+	Summary bool
+	// BoolMarkers renders the values as pass/fail markers when all of them are 0 or 1, such as the result of `bool`.
+	BoolMarkers bool
+	// DurationMetrics renders the values of the metrics whose names end with _seconds as durations such as 1h2m3s.
+	DurationMetrics bool
+	// Units are the units of the metrics set by `\unit`, which take precedence over DurationMetrics.
+	Units map[string]string
+	// NoExponent writes the values in the scientific notation such as 1.5e+07 in plain decimals.
+	NoExponent bool
 }
 
-type Row struct {
-	Columns []string
+func buildTable(qr *promql.QueryResponse, opts *TableOptions) *promql.Table {
+	table := buildResultTable(qr, opts)
+	if opts.BoolMarkers {
+		replaceBoolValues(table)
+	}
+	return table
 }
 
-func buildTable(qr *QueryResponse) *Table {
-	table := Table{}
+func buildResultTable(qr *promql.QueryResponse, opts *TableOptions) *promql.Table {
+	table := promql.Table{}
 
 	if len(qr.Data.ResultRaw) == 0 {
 		return &table
 	}
 
 	switch result := qr.Data.Result.(type) {
-	case ResultScalar:
+	case promql.ResultScalar:
 		// Add header columns.
-		table.Header = []string{"timestamp", "value"}
+		table.Header = append(opts.timestampHeader(), "value")
 
 		// Add row.
 		timestamp := result[0].(float64)
 		value := result[1].(string)
-		table.Rows = []Row{{Columns: []string{formatTimestamp(timestamp), value}}}
+		table.Rows = []promql.Row{{Columns: append(opts.timestampColumns(timestamp), opts.formatValue(nil, value))}}
 		return &table
-	case ResultString:
+	case promql.ResultString:
 		// Add header columns.
-		table.Header = []string{"timestamp", "value"}
+		table.Header = append(opts.timestampHeader(), "value")
 
 		// Add row.
 		timestamp := result[0].(float64)
 		value := result[1].(string)
-		table.Rows = []Row{{Columns: []string{formatTimestamp(timestamp), value}}}
+		table.Rows = []promql.Row{{Columns: append(opts.timestampColumns(timestamp), value)}}
 		return &table
-	case ResultVector:
+	case promql.ResultVector:
 		if len(result) == 0 {
 			return &table
 		}
 
 		// Add header columns.
-		table.Header = []string{"timestamp"}
-		table.Header = append(table.Header, sortedLabelNames(result[0].Metric)...)
+		var metrics []map[string]string
+		for _, timeseries := range result {
+			metrics = append(metrics, timeseries.Metric)
+		}
+		labelNames := opts.visibleLabelNames(unionLabelNames(metrics))
+		table.Header = opts.timestampHeader()
+		table.Header = append(table.Header, labelNames...)
 		table.Header = append(table.Header, "value")
 
 		// Add rows.
 		for _, timeseries := range result {
-			var row Row
+			var row promql.Row
 			timestamp := timeseries.Point[0].(float64)
 			value := timeseries.Point[1].(string)
 
-			row.Columns = append(row.Columns, formatTimestamp(timestamp))
-			for _, labelName := range sortedLabelNames(timeseries.Metric) {
-				row.Columns = append(row.Columns, timeseries.Metric[labelName])
+			row.Columns = append(row.Columns, opts.timestampColumns(timestamp)...)
+			for _, labelName := range labelNames {
+				row.Columns = append(row.Columns, opts.labelValue(labelName, timeseries.Metric[labelName]))
 			}
-			row.Columns = append(row.Columns, value)
+			row.Columns = append(row.Columns, opts.formatValue(timeseries.Metric, value))
 			table.Rows = append(table.Rows, row)
 		}
 		return &table
-	case ResultMatrix:
+	case promql.ResultMatrix:
 		if len(result) == 0 {
 			return &table
 		}
 
 		// Add header columns.
-		table.Header = []string{"timestamp"}
-		table.Header = append(table.Header, sortedLabelNames(result[0].Metric)...)
+		var metrics []map[string]string
+		for _, timeseries := range result {
+			metrics = append(metrics, timeseries.Metric)
+		}
+		labelNames := opts.visibleLabelNames(unionLabelNames(metrics))
+		if opts.Summary {
+			return buildSummaryTable(result, labelNames, opts)
+		}
+		if opts.MatrixLayout == matrixLayoutSeries {
+			return buildSeriesLayoutTable(result, labelNames, opts)
+		}
+		table.Header = opts.timestampHeader()
+		table.Header = append(table.Header, labelNames...)
 		table.Header = append(table.Header, "value")
 
 		// Add rows.
@@ -216,12 +925,12 @@ func buildTable(qr *QueryResponse) *Table {
 				timestamp := point[0].(float64)
 				value := point[1].(string)
 
-				var row Row
-				row.Columns = append(row.Columns, formatTimestamp(timestamp))
-				for _, labelName := range sortedLabelNames(timeseries.Metric) {
-					row.Columns = append(row.Columns, timeseries.Metric[labelName])
+				var row promql.Row
+				row.Columns = append(row.Columns, opts.timestampColumns(timestamp)...)
+				for _, labelName := range labelNames {
+					row.Columns = append(row.Columns, opts.labelValue(labelName, timeseries.Metric[labelName]))
 				}
-				row.Columns = append(row.Columns, value)
+				row.Columns = append(row.Columns, opts.formatValue(timeseries.Metric, value))
 				table.Rows = append(table.Rows, row)
 			}
 		}
@@ -232,6 +941,45 @@ func buildTable(qr *QueryResponse) *Table {
 	}
 }
 
+// Markers for the values of boolean results.
+const (
+	boolMarkerTrue  = "✓"
+	boolMarkerFalse = "✗"
+)
+
+// replaceBoolValues replaces the values, which are always in the last column, with the markers
+// if all of them are 0 or 1. Otherwise, the table is left as-is.
+func replaceBoolValues(table *promql.Table) {
+	if len(table.Rows) == 0 {
+		return
+	}
+	last := len(table.Header) - 1
+	for _, row := range table.Rows {
+		if v := row.Columns[last]; v != "0" && v != "1" {
+			return
+		}
+	}
+	for _, row := range table.Rows {
+		if row.Columns[last] == "1" {
+			row.Columns[last] = boolMarkerTrue
+		} else {
+			row.Columns[last] = boolMarkerFalse
+		}
+	}
+}
+
+// unionLabelNames returns the sorted label names appearing in any of the metrics,
+// since each time series in a result doesn't necessarily have the same set of labels.
+func unionLabelNames(metrics []map[string]string) []string {
+	union := make(map[string]string)
+	for _, metric := range metrics {
+		for labelName := range metric {
+			union[labelName] = ""
+		}
+	}
+	return sortedLabelNames(union)
+}
+
 func sortedLabelNames(labels map[string]string) []string {
 	var labelNames []string
 	for l := range labels {
@@ -262,7 +1010,14 @@ func sortedLabelNames(labels map[string]string) []string {
 	return labelNames
 }
 
+// truncateWithEllipsis truncates the string to the display width, in which wide characters such as CJK and emoji
+// take two columns, so that the truncated cells are aligned in the table.
+func truncateWithEllipsis(s string, maxWidth int) string {
+	return runewidth.Truncate(s, maxWidth, "…")
+}
+
+// formatTimestamp formats the timestamp in float seconds, rounded to milliseconds as Prometheus timestamps are.
 func formatTimestamp(timestamp float64) string {
-	t := time.UnixMicro(int64(timestamp * 1_000_000))
+	t := time.UnixMilli(int64(math.Round(timestamp * 1e3)))
 	return t.Format(time.RFC3339Nano)
 }
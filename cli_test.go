@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// useUTC makes the timestamps formatted in UTC regardless of the local time zone of the machine.
+func useUTC(t *testing.T) {
+	t.Helper()
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	useUTC(t)
+	tests := []struct {
+		timestamp float64
+		want      string
+	}{
+		{timestamp: 1719292597, want: "2024-06-25T05:16:37Z"},
+		{timestamp: 1719292597.171, want: "2024-06-25T05:16:37.171Z"},
+		{timestamp: 1719292597.1, want: "2024-06-25T05:16:37.1Z"},
+		{timestamp: 1719292597.001, want: "2024-06-25T05:16:37.001Z"},
+		{timestamp: 1719292597.999, want: "2024-06-25T05:16:37.999Z"},
+		{timestamp: 0, want: "1970-01-01T00:00:00Z"},
+		{timestamp: 0.001, want: "1970-01-01T00:00:00.001Z"},
+		{timestamp: -0.5, want: "1969-12-31T23:59:59.5Z"},
+		{timestamp: 4102444800.999, want: "2100-01-01T00:00:00.999Z"},
+		// The float64 of these has no exact microseconds.
+		{timestamp: 100000000000.001, want: "5138-11-16T09:46:40.001Z"},
+		{timestamp: 253402300799.999, want: "9999-12-31T23:59:59.999Z"},
+	}
+	for _, tt := range tests {
+		if got := formatTimestamp(tt.timestamp); got != tt.want {
+			t.Errorf("formatTimestamp(%v) = %s, want %s", tt.timestamp, got, tt.want)
+		}
+	}
+}
+
+// useNarrowEllipsis makes the ellipsis take one column regardless of the locale of the machine.
+// The width of the ellipsis is ambiguous, which is two columns in East Asian locales.
+func useNarrowEllipsis(t *testing.T) {
+	t.Helper()
+	eastAsianWidth := runewidth.DefaultCondition.EastAsianWidth
+	runewidth.DefaultCondition.EastAsianWidth = false
+	t.Cleanup(func() { runewidth.DefaultCondition.EastAsianWidth = eastAsianWidth })
+}
+
+func TestTruncateWithEllipsis(t *testing.T) {
+	useNarrowEllipsis(t)
+
+	tests := []struct {
+		s        string
+		maxWidth int
+		want     string
+	}{
+		{s: "prometheus", maxWidth: 10, want: "prometheus"},
+		{s: "prometheus", maxWidth: 5, want: "prom…"},
+		{s: "東京都", maxWidth: 6, want: "東京都"},
+		{s: "東京都", maxWidth: 5, want: "東京…"},
+		// The wide character doesn't fit in the remaining column, so the result is narrower than the width.
+		{s: "東京都", maxWidth: 4, want: "東…"},
+		{s: "a東京", maxWidth: 4, want: "a東…"},
+		{s: "🔥🔥🔥", maxWidth: 4, want: "🔥…"},
+	}
+	for _, tt := range tests {
+		got := truncateWithEllipsis(tt.s, tt.maxWidth)
+		if got != tt.want {
+			t.Errorf("truncateWithEllipsis(%q, %d) = %q, want %q", tt.s, tt.maxWidth, got, tt.want)
+		}
+		if w := runewidth.StringWidth(got); w > tt.maxWidth {
+			t.Errorf("truncateWithEllipsis(%q, %d) takes %d columns", tt.s, tt.maxWidth, w)
+		}
+	}
+}
+
+func TestBuildTable_MaxWidth(t *testing.T) {
+	useUTC(t)
+	useNarrowEllipsis(t)
+	metric := map[string]string{"__name__": "http_requests_total", "job": "東京"}
+	tests := []struct {
+		name string
+		resp *promql.QueryResponse
+		opts TableOptions
+		want *promql.Table
+	}{
+		{
+			name: "vector",
+			resp: newTestResponse("vector", promql.ResultVector{{Metric: metric, Point: []any{1719292597.171, "123456.789"}}}),
+			opts: TableOptions{MaxWidth: 5, TimestampFormat: timestampFormatBoth},
+			want: &promql.Table{
+				Header: []string{"timestamp", "epoch", "__name__", "job", "value"},
+				Rows:   []promql.Row{{Columns: []string{"2024-06-25T05:16:37.171Z", "1719292597.171", "http…", "東京", "123456.789"}}},
+			},
+		},
+		{
+			name: "summary",
+			resp: newTestResponse("matrix", promql.ResultMatrix{{Metric: metric, Points: [][]any{{1719292597.171, "123456.789"}}}}),
+			opts: TableOptions{MaxWidth: 3, Summary: true},
+			want: &promql.Table{
+				Header: []string{"__name__", "job", "min", "avg", "max", "last"},
+				Rows:   []promql.Row{{Columns: []string{"ht…", "東…", "123456.789", "123456.789", "123456.789", "123456.789"}}},
+			},
+		},
+		{
+			name: "scalar",
+			resp: newTestResponse("scalar", promql.ResultScalar{1719292597.171, "123456.789"}),
+			opts: TableOptions{MaxWidth: 3},
+			want: &promql.Table{
+				Header: []string{"timestamp", "value"},
+				Rows:   []promql.Row{{Columns: []string{"2024-06-25T05:16:37.171Z", "123456.789"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildTable(tt.resp, &tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildTable() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestResponse returns the response of the result, of which ResultRaw is set so that it isn't seen as empty.
+func newTestResponse(resultType string, result any) *promql.QueryResponse {
+	return &promql.QueryResponse{Data: promql.Data{ResultType: resultType, Result: result, ResultRaw: []byte("[]")}}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	colorReset   = "\033[0m"
+	colorYellow  = "\033[33m"
+	colorCyan    = "\033[36m"
+	colorGreen   = "\033[32m"
+	colorBlue    = "\033[34m"
+	colorMagenta = "\033[35m"
+	colorGray    = "\033[90m"
+	colorReverse = "\033[7m"
+)
+
+// isTerminal returns true if the writer is a terminal. Wrapped writers such as lockedWriter are unwrapped.
+func isTerminal(w io.Writer) bool {
+	f, ok := unwrapFile(w)
+	return ok && readline.IsTerminal(int(f.Fd()))
+}
+
+// unwrapFile returns the file under the wrapped writers, or false if the writer isn't a file.
+func unwrapFile(w io.Writer) (*os.File, bool) {
+	for {
+		u, ok := w.(interface{ Unwrap() io.Writer })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	f, ok := w.(*os.File)
+	return f, ok
+}
+
+// colorEnabled returns true if colored output should be written to the writer.
+// Color is disabled when the writer is not a terminal or NO_COLOR environment variable is set.
+func colorEnabled(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(w)
+}
+
+func colorize(s, color string) string {
+	return color + s + colorReset
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isCommand returns true if the input is a meta command such as `\cache clear`, rather than a query.
+func isCommand(input string) bool {
+	return strings.HasPrefix(input, `\`)
+}
+
+// queryCommands are the commands taking a query, of which the variables are substituted in the arguments.
+// The others such as `\rename` are not, since `$` means other things in their regexes and replacements.
+var queryCommands = map[string]bool{
+	"analyze": true, "at-range": true, "bottomk": true, "compare-servers": true, "count": true, "diff-query": true,
+	"exemplars": true, "fmt": true, "graph": true, "labels-of": true, "lint": true, "preview": true, "profile": true,
+	"quantile": true, "rate": true, "topk": true, "union": true, "watch-diff": true,
+}
+
+// runCommand runs the meta command. The variables are substituted in the arguments of queryCommands.
+func (c *CLI) runCommand(ctx context.Context, input string) error {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, `\`), " ")
+	args = strings.TrimSpace(args)
+	if queryCommands[name] {
+		var err error
+		if args, err = expandVariables(args, c.vars); err != nil {
+			return err
+		}
+	}
+
+	switch name {
+	case "analyze":
+		return c.runAnalyzeCommand(ctx, args)
+	case "at-range":
+		return c.runAtRangeCommand(ctx, args)
+	case "buildinfo":
+		return c.runBuildInfoCommand(ctx)
+	case "cache":
+		return c.runCacheCommand(args)
+	case "graph":
+		return c.runGraphCommand(ctx, args)
+	case "bottomk", "topk":
+		return c.runTopkCommand(ctx, name, args)
+	case "count":
+		return c.runCountCommand(ctx, args)
+	case "compare-servers":
+		return c.runCompareServersCommand(ctx, args)
+	case "diff-query":
+		return c.runDiffQueryCommand(ctx, args)
+	case "exemplars":
+		return c.runExemplarsCommand(ctx, args)
+	case "export":
+		return c.runExportCommand(args)
+	case "preview":
+		return c.runPreviewCommand(ctx, args)
+	case "pretty":
+		return c.runPrettyCommand(args)
+	case "profile":
+		return c.runProfileCommand(ctx, args)
+	case "quantile":
+		return c.runQuantileCommand(ctx, args)
+	case "rate":
+		return c.runRateCommand(ctx, args)
+	case "replay":
+		return c.runReplayCommand(args)
+	case "reauth":
+		return c.runReauthCommand()
+	case "rename":
+		return c.runRenameCommand(args)
+	case "rename-column":
+		return c.runRenameColumnCommand(args)
+	case "var":
+		return c.runVarCommand(args)
+	case "vars":
+		return c.runVarsCommand(args)
+	case "watch-diff":
+		return c.runWatchDiffCommand(ctx, args)
+	case "width":
+		return c.runWidthCommand(args)
+	case "x":
+		return c.runExpandedCommand(args)
+	case "grep":
+		return c.runGrepCommand(args)
+	case "last":
+		return c.runLastCommand()
+	case "drop":
+		return c.runDropCommand(args)
+	case "env":
+		return c.runEnvCommand()
+	case "fmt":
+		return c.runFmtCommand(ctx, args)
+	case "format":
+		return c.runFormatCommand(args)
+	case "lint":
+		return c.runLintCommand(ctx, args)
+	case "labels-of":
+		return c.runLabelsOfCommand(ctx, args)
+	case "lookback":
+		return c.runLookbackCommand(args)
+	case "map":
+		return c.runMapCommand(args)
+	case "metrics-diff":
+		return c.runMetricsDiffCommand(ctx, args)
+	case "open":
+		return c.runOpenCommand()
+	case "pin":
+		return c.runPinCommand(args)
+	case "runtimeinfo":
+		return c.runRuntimeInfoCommand(ctx)
+	case "scrape":
+		return c.runScrapeCommand(ctx, args)
+	case "server-timeout":
+		return c.runServerTimeoutCommand(args)
+	case "set":
+		return c.runSetCommand(args)
+	case "trim":
+		return c.runTrimCommand(args)
+	case "tsdb":
+		return c.runTSDBCommand(ctx)
+	case "unit":
+		return c.runUnitCommand(args)
+	case "union":
+		return c.runUnionCommand(ctx, args)
+	case "unpin":
+		c.pinnedTime = time.Time{}
+		return nil
+	default:
+		return fmt.Errorf("unknown command: \\%s", name)
+	}
+}
+
+// cutFields returns the first n fields of the arguments separated by any spaces, and the rest as written, such as the
+// query after the options. False is returned if there are not more than n fields.
+func cutFields(args string, n int) ([]string, string, bool) {
+	fields := strings.Fields(args)
+	if len(fields) <= n {
+		return nil, "", false
+	}
+	rest := strings.TrimSpace(args)
+	for _, field := range fields[:n] {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, field))
+	}
+	return fields[:n], rest, true
+}
+
+// runLastCommand runs `\last`, which re-renders the last result with the current settings without querying again.
+func (c *CLI) runLastCommand() error {
+	if c.lastResponse == nil {
+		fmt.Fprintf(c.out, "no previous result\n\n")
+		return nil
+	}
+	c.PrintResult(c.lastResponse, "")
+	return nil
+}
+
+// runLookbackCommand runs `\lookback [duration|off]`, which overrides the lookback delta for the subsequent queries.
+// This requires a server supporting the lookback_delta parameter.
+func (c *CLI) runLookbackCommand(args string) error {
+	switch {
+	case args == "":
+		if c.lookbackDelta == "" {
+			fmt.Fprintf(c.out, "Lookback delta is the server default\n\n")
+		} else {
+			fmt.Fprintf(c.out, "Lookback delta is %s\n\n", c.lookbackDelta)
+		}
+	case args == "off":
+		c.lookbackDelta = ""
+	case isDuration(args):
+		c.lookbackDelta = args
+	default:
+		return errors.New(`usage: \lookback [duration|off]`)
+	}
+	return nil
+}
+
+// runServerTimeoutCommand runs `\server-timeout [duration|off]`, which bounds the evaluation of the subsequent queries
+// on the server. The server aborts the query exceeding it, while the request itself has no timeout.
+func (c *CLI) runServerTimeoutCommand(args string) error {
+	switch {
+	case args == "":
+		if c.serverTimeout == "" {
+			fmt.Fprintf(c.out, "Server timeout is the server default\n\n")
+		} else {
+			fmt.Fprintf(c.out, "Server timeout is %s\n\n", c.serverTimeout)
+		}
+	case args == "off":
+		c.serverTimeout = ""
+	case isDuration(args):
+		c.serverTimeout = args
+	default:
+		return errors.New(`usage: \server-timeout [duration|off]`)
+	}
+	return nil
+}
+
+// runPinCommand runs `\pin <time>`, which sets the evaluation time of the subsequent queries.
+// Relative time such as -1h is resolved when pinned.
+func (c *CLI) runPinCommand(args string) error {
+	if args == "" {
+		return errors.New(`usage: \pin <time>`)
+	}
+	t, err := parseTime(args)
+	if err != nil {
+		return err
+	}
+	c.pinnedTime = t
+	return nil
+}
+
+// runWidthCommand runs `\width [n]`, which shows or sets the maximum display width of a label value.
+func (c *CLI) runWidthCommand(args string) error {
+	if args == "" {
+		if c.tableOptions.MaxWidth == 0 {
+			fmt.Fprintf(c.out, "Max width is not set\n\n")
+		} else {
+			fmt.Fprintf(c.out, "Max width is %d\n\n", c.tableOptions.MaxWidth)
+		}
+		return nil
+	}
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 0 {
+		return fmt.Errorf(`usage: \width [n] (0 for no truncation)`)
+	}
+	c.tableOptions.MaxWidth = n
+	return nil
+}
+
+func (c *CLI) runCacheCommand(args string) error {
+	switch args {
+	case "":
+		stats, ok := c.client.CacheStats()
+		if !ok {
+			fmt.Fprintf(c.out, "Cache is not configured\n\n")
+			return nil
+		}
+		status := "off"
+		if stats.Enabled {
+			status = "on"
+		}
+		fmt.Fprintf(c.out, "Cache is %s (ttl: %s, entries: %d)\n\n", status, stats.TTL, stats.Entries)
+		return nil
+	case "on", "off":
+		if _, ok := c.client.CacheStats(); !ok {
+			return errors.New("query cache is not configured (use -cache-ttl)")
+		}
+		for _, client := range c.clients {
+			if err := client.SetCacheEnabled(args == "on"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "clear":
+		for _, client := range c.clients {
+			client.ClearCache()
+		}
+		return nil
+	default:
+		return fmt.Errorf(`usage: \cache [on|off|clear]`)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand_Variables(t *testing.T) {
+	c := &CLI{out: io.Discard, vars: map[string]string{"job": "node"}}
+	// ${host} is the named group of the regex, not a variable.
+	if err := c.runCommand(context.Background(), `\rename instance (?P<host>.*):\d+ ${host}`); err != nil {
+		t.Fatalf("runCommand() error = %v", err)
+	}
+	if got, want := c.tableOptions.renameLabelValue("instance", "node-1:9100"), "node-1"; got != want {
+		t.Errorf("renamed value = %q, want %q", got, want)
+	}
+
+	// The query given to a command is substituted.
+	if err := c.runCommand(context.Background(), `\lint up{job="$undefined"}`); err == nil || !strings.Contains(err.Error(), "undefined variable") {
+		t.Errorf("runCommand() error = %v, want the undefined variable", err)
+	}
+}
+
+func TestCutFields(t *testing.T) {
+	tests := []struct {
+		args     string
+		n        int
+		want     []string
+		wantRest string
+		wantOK   bool
+	}{
+		{args: "0.99 5m http_request_duration_seconds_bucket", n: 2, want: []string{"0.99", "5m"}, wantRest: "http_request_duration_seconds_bucket", wantOK: true},
+		{args: "0.99  \t5m\t  foo_bucket by job", n: 2, want: []string{"0.99", "5m"}, wantRest: "foo_bucket by job", wantOK: true},
+		// The spaces in the rest are kept.
+		{args: `1h 1m up{job="a  b"}`, n: 2, want: []string{"1h", "1m"}, wantRest: `up{job="a  b"}`, wantOK: true},
+		{args: "1h 1m", n: 2},
+		{args: "", n: 2},
+	}
+	for _, tt := range tests {
+		got, rest, ok := cutFields(tt.args, tt.n)
+		if !reflect.DeepEqual(got, tt.want) || rest != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("cutFields(%q, %d) = %q, %q, %v, want %q, %q, %v", tt.args, tt.n, got, rest, ok, tt.want, tt.wantRest, tt.wantOK)
+		}
+	}
+}
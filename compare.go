@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// defaultCompareTolerance is the relative difference of the values regarded as the same by `\compare-servers`,
+// which absorbs the rounding errors of the different implementations of the functions.
+const defaultCompareTolerance = 1e-9
+
+// runCompareServersCommand runs `\compare-servers [-t <tolerance>] <other-url> <query>`, which shows the values
+// of both servers side by side, and flags the series differing by more than the tolerance.
+func (c *CLI) runCompareServersCommand(ctx context.Context, args string) error {
+	const usage = `usage: \compare-servers [-t <tolerance>] <other-url> <query>`
+	tolerance, args, err := parseThresholdOption(args, "tolerance", defaultCompareTolerance)
+	if err != nil {
+		return err
+	}
+	url, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if url == "" || query == "" {
+		return errors.New(usage)
+	}
+	if c.remoteRead {
+		return errors.New(`\compare-servers is not supported with the remote read`)
+	}
+	other, err := c.otherClient(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	opts := c.queryOptions()
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+	stop := c.PrintProgressingMark()
+	resp, err := c.client.QueryContext(ctx, query, opts)
+	if err != nil {
+		stop()
+		return fmt.Errorf("%s: %w", c.client.Origin(), err)
+	}
+	otherResp, err := other.QueryContext(ctx, query, opts)
+	stop()
+	if err != nil {
+		return fmt.Errorf("%s: %w", other.Origin(), err)
+	}
+
+	values, err := seriesValues(resp)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.client.Origin(), err)
+	}
+	otherValues, err := seriesValues(otherResp)
+	if err != nil {
+		return fmt.Errorf("%s: %w", other.Origin(), err)
+	}
+
+	table, differs := buildCompareTable(values, otherValues, tolerance)
+	c.printTable(table, fmt.Sprintf("A: %s, B: %s, %d of %d series differ",
+		c.client.Origin(), other.Origin(), differs, len(table.Rows)))
+	return nil
+}
+
+// otherClient returns the cached client for another server, created without the headers given by -headers.
+func (c *CLI) otherClient(ctx context.Context, url string) (*promql.Client, error) {
+	if client, ok := c.otherClients[url]; ok {
+		return client, nil
+	}
+	client, err := promql.NewClient(ctx, url, "", "", c.transportOpts, promql.GCPOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if c.otherClients == nil {
+		c.otherClients = make(map[string]*promql.Client)
+	}
+	c.otherClients[url] = client
+	return client, nil
+}
+
+// buildCompareTable builds the table which has the values of both sides with the delta and the ratio for each series,
+// and returns it with the number of the flagged series.
+func buildCompareTable(valuesA, valuesB map[string]string, tolerance float64) (*promql.Table, int) {
+	table := promql.Table{Header: []string{"labels", "value_a", "value_b", "delta", "ratio", "status"}}
+	var differs int
+	for _, pair := range joinSeries(valuesA, valuesB) {
+		a, b := pair.A, pair.B
+		var delta, ratio, status string
+		switch {
+		case !pair.InB:
+			b, status = "-", "only in A"
+		case !pair.InA:
+			a, status = "-", "only in B"
+		default:
+			delta, ratio = formatDelta(a, b), formatRatio(a, b)
+			if !sameValues(a, b, tolerance) {
+				status = "differs"
+			}
+		}
+		if status != "" {
+			differs++
+		}
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{pair.Fingerprint, a, b, delta, ratio, status}})
+	}
+	return &table, differs
+}
+
+// formatRatio returns b / a, or an empty string if the values are not numbers or a is zero.
+func formatRatio(a, b string) string {
+	fa, ok := parseSampleValue(a)
+	if !ok || fa == 0 {
+		return ""
+	}
+	fb, ok := parseSampleValue(b)
+	if !ok {
+		return ""
+	}
+	return formatFloat(roundTo(fb/fa, 6))
+}
+
+// sameValues returns true if the difference of the values is within the tolerance relative to the larger one.
+// NaNs and infinities are the same only if both are the same kind.
+func sameValues(a, b string, tolerance float64) bool {
+	fa, okA := parseSampleValue(a)
+	fb, okB := parseSampleValue(b)
+	if !okA || !okB {
+		return a == b
+	}
+	switch {
+	case math.IsNaN(fa) || math.IsNaN(fb):
+		return math.IsNaN(fa) && math.IsNaN(fb)
+	case math.IsInf(fa, 0) || math.IsInf(fb, 0):
+		return fa == fb
+	}
+	return math.Abs(fa-fb) <= tolerance*math.Max(math.Abs(fa), math.Abs(fb))
+}
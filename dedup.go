@@ -0,0 +1,101 @@
+package main
+
+import "github.com/yfuruyama/promql-cli/pkg/promql"
+
+// dedupResponse collapses the series with the same labels except the given label, keeping the one with the most
+// recent sample, and returns the number of the collapsed series.
+func dedupResponse(resp *promql.QueryResponse, label string) (*promql.QueryResponse, int) {
+	if label == "" {
+		return resp, 0
+	}
+
+	var result any
+	var collapsed int
+	switch r := resp.Data.Result.(type) {
+	case promql.ResultVector:
+		var deduped promql.ResultVector
+		for _, i := range dedupSeries(len(r), label, func(i int) dedupCandidate {
+			return dedupCandidate{metric: r[i].Metric, lastTime: sampleTime(r[i].Point), samples: 1}
+		}) {
+			deduped = append(deduped, r[i])
+		}
+		result, collapsed = deduped, len(r)-len(deduped)
+	case promql.ResultMatrix:
+		var deduped promql.ResultMatrix
+		for _, i := range dedupSeries(len(r), label, func(i int) dedupCandidate {
+			candidate := dedupCandidate{metric: r[i].Metric, samples: len(r[i].Points)}
+			if len(r[i].Points) > 0 {
+				candidate.lastTime = sampleTime(r[i].Points[len(r[i].Points)-1])
+			}
+			return candidate
+		}) {
+			deduped = append(deduped, r[i])
+		}
+		result, collapsed = deduped, len(r)-len(deduped)
+	default:
+		return resp, 0
+	}
+
+	copied := *resp
+	copied.Data.Result = result
+	return &copied, collapsed
+}
+
+// dedupCandidate is what decides which of the duplicate series is kept.
+type dedupCandidate struct {
+	metric   map[string]string
+	lastTime float64
+	samples  int
+}
+
+// preferredTo returns true if the candidate should be kept over the other by dedupResponse.
+func (c dedupCandidate) preferredTo(other dedupCandidate, label string) bool {
+	switch {
+	case c.lastTime != other.lastTime:
+		return c.lastTime > other.lastTime
+	case c.samples != other.samples:
+		return c.samples > other.samples
+	}
+	return c.metric[label] < other.metric[label]
+}
+
+// dedupSeries collapses the n series with the same labels except the given label, and returns the indexes of the kept
+// ones in the order of the first appearance of each.
+func dedupSeries(n int, label string, candidate func(i int) dedupCandidate) []int {
+	var kept []int
+	indexes := make(map[string]int)
+	for i := 0; i < n; i++ {
+		c := candidate(i)
+		key := labelsFingerprint(withoutLabel(c.metric, label))
+		j, ok := indexes[key]
+		if !ok {
+			indexes[key] = len(kept)
+			kept = append(kept, i)
+			continue
+		}
+		if c.preferredTo(candidate(kept[j]), label) {
+			kept[j] = i
+		}
+	}
+	return kept
+}
+
+// withoutLabel returns the copy of the labels without the given label.
+func withoutLabel(labels map[string]string, name string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != name {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// sampleTime returns the timestamp of the sample in the form of [timestamp, value].
+func sampleTime(point []any) float64 {
+	if len(point) == 0 {
+		return 0
+	}
+	timestamp, _ := point[0].(float64)
+	return timestamp
+}
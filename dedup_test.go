@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+func TestDedupResponse_Vector(t *testing.T) {
+	// The samples of a vector have the same timestamp, so the replica is chosen by its name whichever comes first.
+	for _, vector := range []promql.ResultVector{
+		{
+			{Metric: map[string]string{"job": "api", "replica": "b"}, Point: []any{1719292597.171, "2"}},
+			{Metric: map[string]string{"job": "api", "replica": "a"}, Point: []any{1719292597.171, "1"}},
+		},
+		{
+			{Metric: map[string]string{"job": "api", "replica": "a"}, Point: []any{1719292597.171, "1"}},
+			{Metric: map[string]string{"job": "api", "replica": "b"}, Point: []any{1719292597.171, "2"}},
+		},
+	} {
+		resp := &promql.QueryResponse{Data: promql.Data{ResultType: "vector", Result: vector}}
+		got, collapsed := dedupResponse(resp, "replica")
+		want := promql.ResultVector{{Metric: map[string]string{"job": "api", "replica": "a"}, Point: []any{1719292597.171, "1"}}}
+		if !reflect.DeepEqual(got.Data.Result, want) || collapsed != 1 {
+			t.Errorf("dedupResponse() = %v, %d, want %v, 1", got.Data.Result, collapsed, want)
+		}
+	}
+}
+
+func TestDedupResponse_Matrix(t *testing.T) {
+	matrix := promql.ResultMatrix{
+		// replica a has fallen behind, and c has the same last sample as b with a gap.
+		{Metric: map[string]string{"job": "api", "replica": "a"}, Points: [][]any{{100.0, "1"}, {160.0, "1"}}},
+		{Metric: map[string]string{"job": "api", "replica": "b"}, Points: [][]any{{100.0, "1"}, {160.0, "1"}, {220.0, "2"}}},
+		{Metric: map[string]string{"job": "api", "replica": "c"}, Points: [][]any{{100.0, "1"}, {220.0, "2"}}},
+		{Metric: map[string]string{"job": "db", "replica": "a"}, Points: [][]any{{100.0, "5"}}},
+	}
+	resp := &promql.QueryResponse{Data: promql.Data{ResultType: "matrix", Result: matrix}}
+	got, collapsed := dedupResponse(resp, "replica")
+	want := promql.ResultMatrix{matrix[1], matrix[3]}
+	if !reflect.DeepEqual(got.Data.Result, want) || collapsed != 2 {
+		t.Errorf("dedupResponse() = %v, %d, want %v, 2", got.Data.Result, collapsed, want)
+	}
+	if !reflect.DeepEqual(resp.Data.Result, matrix) {
+		t.Errorf("dedupResponse() modified the response: %v", resp.Data.Result)
+	}
+}
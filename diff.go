@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runDiffQueryCommand runs `\diff-query <query1> | <query2>`, which compares the results of the two instant queries
+// series by series.
+func (c *CLI) runDiffQueryCommand(ctx context.Context, args string) error {
+	q1, q2, ok := splitQueryPair(args)
+	if !ok {
+		return errors.New(`usage: \diff-query <query1> | <query2>`)
+	}
+
+	stop := c.PrintProgressingMark()
+	resp1, _, err1 := c.query(ctx, q1)
+	resp2, _, err2 := c.query(ctx, q2)
+	stop()
+	if err1 != nil {
+		return fmt.Errorf("query1: %w", err1)
+	}
+	if err2 != nil {
+		return fmt.Errorf("query2: %w", err2)
+	}
+
+	values1, err := seriesValues(resp1)
+	if err != nil {
+		return fmt.Errorf("query1: %w", err)
+	}
+	values2, err := seriesValues(resp2)
+	if err != nil {
+		return fmt.Errorf("query2: %w", err)
+	}
+
+	table := buildDiffTable(values1, values2)
+	summary := fmt.Sprintf("%d series in diff", len(table.Rows))
+	if len(table.Rows) == 0 {
+		summary = "Empty result"
+	}
+	c.printTable(table, summary)
+	return nil
+}
+
+// splitQueryPair splits the input into two queries at the `|`.
+func splitQueryPair(input string) (string, string, bool) {
+	queries, ok := splitQueries(input)
+	if !ok || len(queries) != 2 {
+		return "", "", false
+	}
+	return queries[0], queries[1], true
+}
+
+// splitQueries splits the input into queries at the `|` outside of string literals.
+// False is returned if any of the queries is empty.
+func splitQueries(input string) ([]string, bool) {
+	var queries []string
+	start := 0
+	for i := 0; i <= len(input); i++ {
+		if i == len(input) || input[i] == '|' {
+			query := strings.TrimSpace(input[start:i])
+			if query == "" {
+				return nil, false
+			}
+			queries = append(queries, query)
+			start = i + 1
+			continue
+		}
+		switch input[i] {
+		case '"', '\'', '`':
+			end, err := scanString(input, i)
+			if err != nil {
+				return nil, false
+			}
+			i = end - 1
+		}
+	}
+	return queries, true
+}
+
+// seriesValues returns the values of the instant query result keyed by the fingerprint of the labels.
+func seriesValues(resp *promql.QueryResponse) (map[string]string, error) {
+	values := make(map[string]string)
+	switch result := resp.Data.Result.(type) {
+	case promql.ResultScalar:
+		values[labelsFingerprint(nil)] = result[1].(string)
+	case promql.ResultVector:
+		for _, timeseries := range result {
+			values[labelsFingerprint(timeseries.Metric)] = timeseries.Point[1].(string)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported result type: %q", resp.Data.ResultType)
+	}
+	return values, nil
+}
+
+// labelsFingerprint returns the string identifying the label set regardless of the order of labels.
+func labelsFingerprint(labels map[string]string) string {
+	return formatMetric(labels)
+}
+
+// buildDiffTable builds the table which has the values of both sides and the delta for each series.
+// Series present in only one side are marked in the delta column.
+func buildDiffTable(values1, values2 map[string]string) *promql.Table {
+	table := promql.Table{Header: []string{"labels", "value1", "value2", "delta"}}
+	for _, pair := range joinSeries(values1, values2) {
+		v1, v2 := pair.A, pair.B
+		var delta string
+		switch {
+		case !pair.InB:
+			v2, delta = "-", "only in query1"
+		case !pair.InA:
+			v1, delta = "-", "only in query2"
+		default:
+			delta = formatDelta(v1, v2)
+		}
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{pair.Fingerprint, v1, v2, delta}})
+	}
+	return &table
+}
+
+// seriesPair is the values of a series on both sides joined by joinSeries.
+type seriesPair struct {
+	Fingerprint string
+	A, B        string
+	// InA and InB are false if the series is missing on the side.
+	InA, InB bool
+}
+
+// joinSeries joins the values by the label fingerprints returned by seriesValues, which is the full outer join
+// sorted by the fingerprints, for the commands comparing two results series by series.
+func joinSeries(valuesA, valuesB map[string]string) []seriesPair {
+	fingerprints := make([]string, 0, len(valuesA)+len(valuesB))
+	for fp := range valuesA {
+		fingerprints = append(fingerprints, fp)
+	}
+	for fp := range valuesB {
+		if _, ok := valuesA[fp]; !ok {
+			fingerprints = append(fingerprints, fp)
+		}
+	}
+	sort.Strings(fingerprints)
+
+	pairs := make([]seriesPair, len(fingerprints))
+	for i, fp := range fingerprints {
+		pairs[i].Fingerprint = fp
+		pairs[i].A, pairs[i].InA = valuesA[fp]
+		pairs[i].B, pairs[i].InB = valuesB[fp]
+	}
+	return pairs
+}
+
+// parseThresholdOption parses the leading `-t <number>` option, and returns it with the rest of the arguments.
+func parseThresholdOption(args string, name string, defaultValue float64) (float64, string, error) {
+	option, rest, _ := strings.Cut(args, " ")
+	if option != "-t" {
+		return defaultValue, args, nil
+	}
+	value, rest, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	t, err := strconv.ParseFloat(value, 64)
+	if err != nil || t < 0 || math.IsNaN(t) || math.IsInf(t, 0) {
+		return 0, "", fmt.Errorf("invalid %s: %q, expected a non-negative number", name, value)
+	}
+	return t, strings.TrimSpace(rest), nil
+}
+
+// formatDelta returns value2 - value1, or an empty string if the values are not numbers.
+func formatDelta(value1, value2 string) string {
+	f1, ok := parseSampleValue(value1)
+	if !ok {
+		return ""
+	}
+	f2, ok := parseSampleValue(value2)
+	if !ok {
+		return ""
+	}
+	return formatFloat(f2 - f1)
+}
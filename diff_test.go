@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinSeries(t *testing.T) {
+	got := joinSeries(
+		map[string]string{`{job="a"}`: "1", `{job="b"}`: "2"},
+		map[string]string{`{job="b"}`: "3", `{job="c"}`: "4"},
+	)
+	want := []seriesPair{
+		{Fingerprint: `{job="a"}`, A: "1", InA: true},
+		{Fingerprint: `{job="b"}`, A: "2", B: "3", InA: true, InB: true},
+		{Fingerprint: `{job="c"}`, B: "4", InB: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("joinSeries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseThresholdOption(t *testing.T) {
+	tests := []struct {
+		args     string
+		want     float64
+		wantRest string
+		wantErr  bool
+	}{
+		{args: "10s up", want: 0.5, wantRest: "10s up"},
+		{args: "-t 0.01 10s up", want: 0.01, wantRest: "10s up"},
+		{args: "-t  0 10s up", want: 0, wantRest: "10s up"},
+		{args: "-t 1e3 up", want: 1000, wantRest: "up"},
+		{args: "-t", wantErr: true},
+		{args: "-t abc up", wantErr: true},
+		{args: "-t -1 up", wantErr: true},
+		{args: "-t NaN up", wantErr: true},
+		{args: "-t +Inf up", wantErr: true},
+		{args: "-t Inf up", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, rest, err := parseThresholdOption(tt.args, "threshold", 0.5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseThresholdOption() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || rest != tt.wantRest {
+				t.Errorf("parseThresholdOption() = %v, %q, want %v, %q", got, rest, tt.want, tt.wantRest)
+			}
+		})
+	}
+}
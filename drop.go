@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runDropCommand runs `\drop <label>...`, which hides the label columns from the output.
+// `\drop` lists the hidden labels, and `\drop clear` shows all of them again.
+func (c *CLI) runDropCommand(args string) error {
+	labels := strings.Fields(strings.ReplaceAll(args, ",", " "))
+	switch {
+	case len(labels) == 0:
+		if len(c.tableOptions.DropLabels) == 0 {
+			fmt.Fprintf(c.out, "No dropped labels\n\n")
+			return nil
+		}
+		fmt.Fprintf(c.out, "%s\n\n", strings.Join(c.tableOptions.DropLabels, ", "))
+		return nil
+	case len(labels) == 1 && labels[0] == "clear":
+		c.tableOptions.DropLabels = nil
+		return nil
+	default:
+		for _, label := range labels {
+			if !c.tableOptions.isDropped(label) {
+				c.tableOptions.DropLabels = append(c.tableOptions.DropLabels, label)
+			}
+		}
+		return nil
+	}
+}
+
+// parseDropLabels parses the comma separated label names given by -drop-labels.
+func parseDropLabels(s string) []string {
+	var labels []string
+	for _, label := range strings.Split(s, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func (o *TableOptions) isDropped(label string) bool {
+	for _, dropped := range o.DropLabels {
+		if dropped == label {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleLabelNames returns the label names except for the dropped ones.
+func (o *TableOptions) visibleLabelNames(labelNames []string) []string {
+	if len(o.DropLabels) == 0 {
+		return labelNames
+	}
+	var visible []string
+	for _, labelName := range labelNames {
+		if !o.isDropped(labelName) {
+			visible = append(visible, labelName)
+		}
+	}
+	return visible
+}
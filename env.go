@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runEnvCommand runs `\env`, which shows the resolved connection details of each server without network calls.
+func (c *CLI) runEnvCommand() error {
+	for _, client := range c.clients {
+		fmt.Fprintf(c.out, "url:     %s\n", client.BaseURL())
+		fmt.Fprintf(c.out, "auth:    %s\n", client.AuthMode())
+
+		timeout := "none"
+		if client.Timeout() > 0 {
+			timeout = client.Timeout().String()
+		}
+		fmt.Fprintf(c.out, "timeout: %s\n", timeout)
+
+		proxy, err := client.Proxy()
+		if err != nil {
+			return err
+		}
+		if proxy == "" {
+			proxy = "none"
+		}
+		fmt.Fprintf(c.out, "proxy:   %s\n", proxy)
+
+		header := client.Header()
+		var names []string
+		for name := range header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			fmt.Fprintf(c.out, "headers: none\n")
+		} else {
+			fmt.Fprintf(c.out, "headers:\n")
+		}
+		for _, name := range names {
+			for _, val := range header[name] {
+				if promql.IsSensitiveHeader(name) {
+					val = "REDACTED"
+				}
+				fmt.Fprintf(c.out, "  %s: %s\n", name, val)
+			}
+		}
+		fmt.Fprintln(c.out)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runExemplarsCommand runs `\exemplars <selector> <start> <end>`, which shows the exemplars of the series
+// with their labels such as trace_id, so that the metrics can be linked to the traces.
+func (c *CLI) runExemplarsCommand(ctx context.Context, args string) error {
+	usage := errors.New(`usage: \exemplars <selector> <start> <end>`)
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		return usage
+	}
+	start, err := parseTime(fields[len(fields)-2])
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(fields[len(fields)-1])
+	if err != nil {
+		return err
+	}
+	if end.Before(start) {
+		return errors.New("end must not be before start")
+	}
+	// The selector may contain spaces, such as `up{job="a", instance="b"}`.
+	selector := strings.Join(fields[:len(fields)-2], " ")
+
+	stop := c.PrintProgressingMark()
+	series, err := c.client.QueryExemplarsContext(ctx, selector, start, end)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	table := buildExemplarTable(series, &c.tableOptions)
+	summary := fmt.Sprintf("%d exemplars in result", len(table.Rows))
+	if len(table.Rows) == 0 {
+		summary = "No exemplars"
+	}
+	c.printTable(table, summary)
+	return nil
+}
+
+// buildExemplarTable builds the table which has one row per exemplar, with the series it belongs to
+// and a column for each exemplar label.
+func buildExemplarTable(series []promql.ExemplarSeries, opts *TableOptions) *promql.Table {
+	var labelSets []map[string]string
+	for _, s := range series {
+		for _, exemplar := range s.Exemplars {
+			labelSets = append(labelSets, exemplar.Labels)
+		}
+	}
+	labelNames := unionLabelNames(labelSets)
+
+	table := promql.Table{Header: append(append(opts.timestampHeader(), "series"), append(labelNames, "value")...)}
+	for _, s := range series {
+		metric := formatMetric(s.SeriesLabels)
+		for _, exemplar := range s.Exemplars {
+			row := promql.Row{Columns: append(opts.timestampColumns(exemplar.Timestamp), metric)}
+			for _, labelName := range labelNames {
+				row.Columns = append(row.Columns, exemplar.Labels[labelName])
+			}
+			row.Columns = append(row.Columns, exemplar.Value)
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	return &table
+}
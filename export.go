@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const grafanaPanelTitleMaxLength = 60
+
+type grafanaPanel struct {
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	Datasource grafanaDatasource `json:"datasource"`
+	Targets    []grafanaTarget   `json:"targets"`
+	GridPos    grafanaGridPos    `json:"gridPos"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaTarget struct {
+	RefID      string            `json:"refId"`
+	Expr       string            `json:"expr"`
+	Datasource grafanaDatasource `json:"datasource"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// runExportCommand runs `\export grafana [file]`, which exports the last query as a Grafana panel JSON.
+func (c *CLI) runExportCommand(args string) error {
+	target, file, _ := strings.Cut(args, " ")
+	file = strings.TrimSpace(file)
+	if target != "grafana" {
+		return errors.New(`usage: \export grafana [file]`)
+	}
+	if c.lastQuery == "" {
+		return errors.New("no previous query")
+	}
+
+	b, err := json.MarshalIndent(newGrafanaPanel(c.lastQuery), "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if file == "" {
+		_, err := c.out.Write(b)
+		return err
+	}
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Grafana panel is written to %s\n\n", file)
+	return nil
+}
+
+func newGrafanaPanel(query string) *grafanaPanel {
+	// The datasource is left as a variable to be replaced when importing to a dashboard.
+	datasource := grafanaDatasource{Type: "prometheus", UID: "${DS_PROMETHEUS}"}
+
+	title := query
+	if runes := []rune(title); len(runes) > grafanaPanelTitleMaxLength {
+		title = string(runes[:grafanaPanelTitleMaxLength-1]) + "…"
+	}
+
+	return &grafanaPanel{
+		Type:       "timeseries",
+		Title:      title,
+		Datasource: datasource,
+		Targets:    []grafanaTarget{{RefID: "A", Expr: query, Datasource: datasource}},
+		GridPos:    grafanaGridPos{H: 8, W: 12, X: 0, Y: 0},
+	}
+}
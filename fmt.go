@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runFmtCommand runs `\fmt <query>`, which prints the query formatted by the server.
+// If the server doesn't support formatting, the query is formatted locally on a single line.
+func (c *CLI) runFmtCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \fmt <query>`)
+	}
+
+	stop := c.PrintProgressingMark()
+	formatted, err := c.client.FormatQueryContext(ctx, args)
+	stop()
+	if errors.Is(err, promql.ErrFormatQueryUnsupported) {
+		formatted, err = formatPromQL(args)
+		if err == nil {
+			fmt.Fprintf(c.errOut, "The server doesn't support format_query, so the query is formatted locally\n")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s\n\n", formatted)
+	return nil
+}
+
+// formatPromQL formats the query on a single line with the canonical spacing, such as `sum by (job) (rate(up[5m]))`.
+// Comments are removed. Unlike the server, the query isn't validated beyond tokenizing.
+func formatPromQL(query string) (string, error) {
+	tokens, err := lexPromQL(query)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	var prev *token
+	// depth is the nesting of braces and brackets, in which only commas are followed by a space.
+	depth := 0
+	unary := false
+	for i := range tokens {
+		t := &tokens[i]
+		if t.typ == tokenEOF || t.typ == tokenComment {
+			continue
+		}
+		if prev != nil && !unary && needsSpace(prev, t, depth) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.val)
+
+		switch t.typ {
+		case tokenLeftBrace, tokenLeftBracket:
+			depth++
+		case tokenRightBrace, tokenRightBracket:
+			depth--
+		}
+		// The sign of a number or an expression is unary when it doesn't follow an operand.
+		unary = t.typ == tokenOperator && (t.val == "-" || t.val == "+") && (prev == nil || !isOperand(prev))
+		prev = t
+	}
+	return b.String(), nil
+}
+
+// needsSpace returns true if a space is needed between the tokens.
+func needsSpace(prev, t *token, depth int) bool {
+	switch {
+	case prev.typ == tokenLeftParen || prev.typ == tokenLeftBrace || prev.typ == tokenLeftBracket:
+		return false
+	case t.typ == tokenRightParen || t.typ == tokenRightBrace || t.typ == tokenRightBracket || t.typ == tokenComma:
+		return false
+	case prev.typ == tokenComma:
+		return true
+	case depth > 0:
+		// Label matchers such as job="a", and ranges such as [5m:1m].
+		return false
+	case t.typ == tokenLeftBracket:
+		return false
+	case t.typ == tokenLeftParen && prev.typ == tokenKeyword:
+		// start() and end() of the @ modifier are keywords, unlike the others such as `by (job)`.
+		name := strings.ToLower(prev.val)
+		return name != "start" && name != "end"
+	case t.typ == tokenLeftParen || t.typ == tokenLeftBrace:
+		// Function calls and selectors such as rate(...) and up{...}, unlike `) (`.
+		return prev.typ != tokenIdentifier
+	}
+	return true
+}
+
+// isOperand returns true if the token ends an operand, after which `-` and `+` are binary operators.
+func isOperand(t *token) bool {
+	switch t.typ {
+	case tokenIdentifier, tokenString, tokenNumber, tokenDuration, tokenRightParen, tokenRightBrace, tokenRightBracket:
+		return true
+	}
+	return false
+}
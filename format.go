@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const (
+	formatTable    = "table"
+	formatCSV      = "csv"
+	formatTSV      = "tsv"
+	formatMarkdown = "markdown"
+	formatExpanded = "expanded"
+	formatRawJSON  = "raw-json"
+)
+
+var outputFormats = []string{formatTable, formatCSV, formatTSV, formatMarkdown, formatExpanded, formatRawJSON}
+
+// formatAliases are the short names accepted in addition to the format names.
+var formatAliases = map[string]string{
+	"json": formatRawJSON,
+}
+
+func isValidFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFormat returns the format of the name or the alias, or false if it's unknown.
+func resolveFormat(name string) (string, bool) {
+	if format, ok := formatAliases[name]; ok {
+		return format, true
+	}
+	return name, isValidFormat(name)
+}
+
+// parseFormatOverride parses the format given for just one query, either by the prefix such as `\json up` or by
+// the trailing pragma such as `up \format json`. The format is empty if the input has no override.
+func parseFormatOverride(input string) (query, format string, err error) {
+	if isCommand(input) {
+		name, rest, _ := strings.Cut(strings.TrimPrefix(input, `\`), " ")
+		format, ok := resolveFormat(name)
+		if !ok {
+			return input, "", nil
+		}
+		query = strings.TrimSpace(rest)
+		if query == "" {
+			return "", "", fmt.Errorf(`usage: \%s <query>`, name)
+		}
+		return query, format, nil
+	}
+
+	i := strings.LastIndex(input, `\format `)
+	if i < 0 {
+		return input, "", nil
+	}
+	name := strings.TrimSpace(input[i+len(`\format `):])
+	// Only a word at the end is the pragma, and the others like `\format` in a string literal are left to the server.
+	if !isFormatWord(name) {
+		return input, "", nil
+	}
+	format, ok := resolveFormat(name)
+	if !ok {
+		return "", "", fmt.Errorf("unknown format: %q (available: %s)", name, strings.Join(outputFormats, ", "))
+	}
+	query = strings.TrimSpace(input[:i])
+	if query == "" {
+		return "", "", fmt.Errorf(`usage: <query> \format <name>`)
+	}
+	return query, format, nil
+}
+
+func isFormatWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// tableWriters are the writers of the formats processed by other tools, for which nothing but the values is written.
+var tableWriters = map[string]func(io.Writer, *promql.Table, bool) error{
+	formatCSV:      promql.WriteCSV,
+	formatTSV:      promql.WriteTSV,
+	formatMarkdown: promql.WriteMarkdown,
+	formatRawJSON:  promql.WriteTableJSON,
+}
+
+// printTable writes the table built by commands in the output format, followed by the summary such as "3 series in diff" if any.
+// For the formats processed by other tools, the summary is written to stderr so as not to mix it with the values.
+func (c *CLI) printTable(table *promql.Table, summary string) {
+	if write, ok := tableWriters[c.format]; ok {
+		if c.format == formatRawJSON && c.prettyJSON {
+			write = promql.WriteIndentedTableJSON
+		}
+		if len(table.Rows) > 0 {
+			if err := write(c.out, table, c.noHeader); err != nil {
+				c.PrintInteractiveError(err)
+			}
+		}
+		if summary != "" {
+			fmt.Fprintln(c.errOut, summary)
+		}
+		return
+	}
+
+	if len(table.Rows) > 0 {
+		if c.format == formatExpanded {
+			if err := promql.WriteExpanded(c.out, table); err != nil {
+				c.PrintInteractiveError(err)
+			}
+		} else {
+			c.renderTable(table)
+		}
+	}
+	if summary != "" {
+		fmt.Fprintln(c.out, summary)
+	}
+	fmt.Fprintln(c.out)
+}
+
+// runExpandedCommand runs `\x [on|off]`, which toggles the expanded format.
+// Turning it off restores the format used before, such as csv, rather than the table format.
+func (c *CLI) runExpandedCommand(args string) error {
+	on := c.format != formatExpanded
+	switch args {
+	case "":
+	case "on":
+		on = true
+	case "off":
+		on = false
+	default:
+		return fmt.Errorf(`usage: \x [on|off]`)
+	}
+	switch {
+	case on:
+		c.setFormat(formatExpanded)
+	case c.format == formatExpanded:
+		c.setFormat(c.formatBeforeExpanded)
+	}
+	fmt.Fprintf(c.out, "Expanded display is %s\n\n", onOff(c.format == formatExpanded))
+	return nil
+}
+
+// setFormat sets the output format of the session, remembering the current one when switching to the expanded
+// format so that `\x off` can restore it. The table format is set if the format is empty.
+func (c *CLI) setFormat(format string) {
+	if format == "" {
+		format = formatTable
+	}
+	if format == formatExpanded && c.format != formatExpanded {
+		c.formatBeforeExpanded = c.format
+	}
+	c.format = format
+}
+
+// runFormatCommand runs `\format [name]`, which shows or sets the output format.
+func (c *CLI) runFormatCommand(args string) error {
+	if args == "" {
+		fmt.Fprintf(c.out, "Output format is %s\n\n", c.format)
+		return nil
+	}
+	format, ok := resolveFormat(args)
+	if !ok {
+		return fmt.Errorf("unknown format: %q (available: %s)", args, strings.Join(outputFormats, ", "))
+	}
+	c.setFormat(format)
+	return nil
+}
+
+// runPrettyCommand runs `\pretty [on|off]`, which toggles indenting the JSON output.
+func (c *CLI) runPrettyCommand(args string) error {
+	switch args {
+	case "":
+		c.prettyJSON = !c.prettyJSON
+	case "on", "off":
+		c.prettyJSON = args == "on"
+	default:
+		return fmt.Errorf(`usage: \pretty [on|off]`)
+	}
+	fmt.Fprintf(c.out, "Pretty JSON is %s\n\n", onOff(c.prettyJSON))
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestRunExpandedCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		commands []string
+		want     string
+	}{
+		{name: "toggle from table", format: formatTable, commands: []string{"", ""}, want: formatTable},
+		{name: "toggle from csv", format: formatCSV, commands: []string{"", ""}, want: formatCSV},
+		{name: "on and off from csv", format: formatCSV, commands: []string{"on", "off"}, want: formatCSV},
+		{name: "on twice", format: formatTSV, commands: []string{"on", "on", "off"}, want: formatTSV},
+		{name: "off without expanded", format: formatCSV, commands: []string{"off"}, want: formatCSV},
+		// The expanded format given at startup has no format to restore.
+		{name: "off from startup", format: formatExpanded, commands: []string{"off"}, want: formatTable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CLI{out: io.Discard, format: tt.format}
+			for _, args := range tt.commands {
+				if err := c.runExpandedCommand(args); err != nil {
+					t.Fatalf("runExpandedCommand(%q) error = %v", args, err)
+				}
+			}
+			if c.format != tt.want {
+				t.Errorf("format = %q, want %q", c.format, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExpandedCommand_AfterFormatCommand(t *testing.T) {
+	c := &CLI{out: io.Discard, format: formatCSV}
+	for _, step := range []func() error{
+		func() error { return c.runExpandedCommand("on") },
+		func() error { return c.runFormatCommand(formatMarkdown) },
+		func() error { return c.runFormatCommand(formatExpanded) },
+		func() error { return c.runExpandedCommand("off") },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The format before the latest switch to the expanded format is restored.
+	if c.format != formatMarkdown {
+		t.Errorf("format = %q, want %q", c.format, formatMarkdown)
+	}
+	if err := c.runExpandedCommand("maybe"); err == nil {
+		t.Error(`runExpandedCommand("maybe") returned no error`)
+	}
+}
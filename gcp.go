@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+// runReauthCommand runs `\reauth`, which forces to refresh the access token for Google Cloud Monitoring.
+func (c *CLI) runReauthCommand() error {
+	for _, client := range c.clients {
+		if err := client.Reauth(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.out, "Access token is refreshed\n\n")
+	return nil
+}
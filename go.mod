@@ -1,15 +1,15 @@
 module github.com/yfuruyama/promql-cli
 
-go 1.20
+go 1.21
 
 require (
 	github.com/chzyer/readline v1.5.1
+	github.com/mattn/go-runewidth v0.0.9
 	github.com/olekukonko/tablewriter v0.0.5
 	golang.org/x/oauth2 v0.21.0
 )
 
 require (
 	cloud.google.com/go/compute/metadata v0.3.0 // indirect
-	github.com/mattn/go-runewidth v0.0.9 // indirect
 	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5 // indirect
 )
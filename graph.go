@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// graphTemplate is the page of the chart. Chart.js is loaded from the CDN, so the browser needs network access to
+// render it. It isn't embedded because this tool is a single binary without assets, and Chart.js is too large to inline.
+var graphTemplate = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Query}}</title>
+<script src="https://cdn.jsdelivr.net/npm/chart.js@4"></script>
+</head>
+<body>
+<h3><code>{{.Query}}</code></h3>
+<canvas id="chart"></canvas>
+<script>
+const datasets = {{.Datasets}};
+new Chart(document.getElementById("chart"), {
+  type: "line",
+  data: { datasets: datasets },
+  options: {
+    animation: false,
+    parsing: false,
+    elements: { point: { radius: 0 } },
+    scales: {
+      x: { type: "linear", ticks: { callback: (v) => new Date(v).toLocaleString() } },
+    },
+    plugins: {
+      tooltip: { callbacks: { title: (items) => new Date(items[0].parsed.x).toLocaleString() } },
+    },
+  },
+});
+</script>
+</body>
+</html>
+`))
+
+type graphDataset struct {
+	Label string       `json:"label"`
+	Data  []graphPoint `json:"data"`
+}
+
+type graphPoint struct {
+	X int64 `json:"x"`
+	// Y is nil for values that can't be represented in JSON, such as NaN and Inf.
+	Y *float64 `json:"y"`
+}
+
+// runGraphCommand runs `\graph <range> <step> <query>`, which renders the range query result as a chart in the browser.
+func (c *CLI) runGraphCommand(ctx context.Context, args string) error {
+	fields, query, ok := cutFields(args, 2)
+	if !ok {
+		return fmt.Errorf(`usage: \graph <range> <step> <query>`)
+	}
+	rangeDuration, err := parsePromDuration(fields[0])
+	if err != nil || rangeDuration <= 0 {
+		return fmt.Errorf("invalid range: %q, expected a duration such as 1h or 1d", fields[0])
+	}
+	step, err := parsePromDuration(fields[1])
+	if err != nil || step <= 0 {
+		return fmt.Errorf("invalid step: %q, expected a duration such as 1m", fields[1])
+	}
+
+	end := time.Now()
+	if !c.pinnedTime.IsZero() {
+		end = c.pinnedTime
+	}
+	stop := c.PrintProgressingMark()
+	resp, err := c.client.QueryRangeContext(ctx, query, end.Add(-rangeDuration), end, step)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	matrix, ok := resp.Data.Result.(promql.ResultMatrix)
+	if !ok {
+		return fmt.Errorf("unexpected result type: %q", resp.Data.ResultType)
+	}
+	if len(matrix) == 0 {
+		fmt.Fprintf(c.out, "Empty result\n\n")
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "promql-graph-*.html")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data := struct {
+		Query    string
+		Datasets []graphDataset
+	}{
+		Query:    query,
+		Datasets: buildGraphDatasets(matrix),
+	}
+	if err := graphTemplate.Execute(f, data); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Graph is written to %s (Chart.js is loaded from the CDN, which needs network access)\n\n", f.Name())
+	return openBrowser(f.Name())
+}
+
+func buildGraphDatasets(matrix promql.ResultMatrix) []graphDataset {
+	var datasets []graphDataset
+	for _, timeseries := range matrix {
+		dataset := graphDataset{Label: formatMetric(timeseries.Metric)}
+		for _, point := range timeseries.Points {
+			timestamp := point[0].(float64)
+			p := graphPoint{X: int64(timestamp * 1000)}
+			if v, ok := parseSampleValue(point[1].(string)); ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+				p.Y = &v
+			}
+			dataset.Data = append(dataset.Data, p)
+		}
+		datasets = append(datasets, dataset)
+	}
+	return datasets
+}
+
+// formatMetric formats the labels in the PromQL selector form such as `up{job="prometheus"}`.
+func formatMetric(metric map[string]string) string {
+	var name string
+	var labels []string
+	for _, labelName := range sortedLabelNames(metric) {
+		if labelName == "__name__" {
+			name = metric[labelName]
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("%s=%q", labelName, metric[labelName]))
+	}
+	if name != "" && len(labels) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(labels, ", ") + "}"
+}
+
+// openBrowser opens the URL or the file path in the default browser.
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const colorBoldRed = "\033[1;31m"
+
+// runGrepCommand runs `\grep [-o|-v] <regex>`, which searches all the columns of the last result including the value.
+// Matches are highlighted when color is enabled. -o shows only the matching rows, and -v shows only the others.
+func (c *CLI) runGrepCommand(args string) error {
+	var onlyMatching, invert bool
+	switch option, rest, _ := strings.Cut(args, " "); option {
+	case "-o":
+		onlyMatching, args = true, strings.TrimSpace(rest)
+	case "-v":
+		invert, args = true, strings.TrimSpace(rest)
+	}
+	if args == "" {
+		return errors.New(`usage: \grep [-o|-v] <regex>`)
+	}
+	re, err := regexp.Compile(args)
+	if err != nil {
+		return fmt.Errorf("invalid regex: %v", err)
+	}
+	if c.lastResponse == nil {
+		fmt.Fprintf(c.out, "no previous result\n\n")
+		return nil
+	}
+
+	table := buildTable(c.lastResponse, &c.tableOptions)
+	c.renameColumns(table)
+	// Matches are highlighted only in the formats for humans.
+	_, forTools := tableWriters[c.format]
+	highlight := colorEnabled(c.out) && !forTools
+	var rows []promql.Row
+	var matched int
+	for _, row := range table.Rows {
+		match := false
+		for i, column := range row.Columns {
+			if !re.MatchString(column) {
+				continue
+			}
+			match = true
+			if highlight && !invert {
+				row.Columns[i] = re.ReplaceAllStringFunc(column, func(s string) string {
+					return colorize(s, colorBoldRed)
+				})
+			}
+		}
+		if match {
+			matched++
+		}
+		if (onlyMatching && !match) || (invert && match) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	total := len(table.Rows)
+	table.Rows = rows
+
+	c.printTable(table, fmt.Sprintf("%d of %d rows matched", matched, total))
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const (
+	defaultCardinalityWarn = 10000
+	defaultMaxSamplesWarn  = 100000
+
+	// assumedScrapeInterval is used to estimate the number of samples in a range, since the actual one is unknown.
+	assumedScrapeInterval = 15 * time.Second
+)
+
+// queryWarning returns the warning message if the query looks expensive, or empty otherwise.
+// The static estimation by the local parser is checked before the one asking the server.
+func (c *CLI) queryWarning(ctx context.Context, query string) string {
+	if warning := c.samplesWarning(query); warning != "" {
+		return warning
+	}
+	return c.cardinalityWarning(ctx, query)
+}
+
+// samplesWarning returns a warning message if the query reads more samples per series than the threshold.
+func (c *CLI) samplesWarning(query string) string {
+	if c.maxSamplesWarn <= 0 {
+		return ""
+	}
+	samples, err := estimateSamples(query)
+	if err != nil || samples <= int64(c.maxSamplesWarn) {
+		return ""
+	}
+	return fmt.Sprintf("The query reads ~%d samples per series assuming the %s scrape interval, which exceeds the threshold %d.",
+		samples, assumedScrapeInterval, c.maxSamplesWarn)
+}
+
+// estimateSamples estimates the number of samples per series read by the range selectors and the subqueries.
+func estimateSamples(query string) (int64, error) {
+	tokens, err := lexPromQL(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var samples int64
+	for i, t := range tokens {
+		if t.typ != tokenLeftBracket || tokens[i+1].typ != tokenDuration {
+			continue
+		}
+		d, err := parsePromDuration(tokens[i+1].val)
+		if err != nil {
+			return 0, err
+		}
+		samples += int64(d / assumedScrapeInterval)
+	}
+	return samples, nil
+}
+
+// cardinalityWarning returns a warning message if the bare selector matches more series than the threshold,
+// which is estimated by the Series API.
+func (c *CLI) cardinalityWarning(ctx context.Context, query string) string {
+	// The series API is not available on the remote read endpoint.
+	if c.cardinalityWarn <= 0 || c.remoteRead {
+		return ""
+	}
+	selector, err := parseBareSelector(query)
+	if err != nil {
+		return ""
+	}
+
+	start, end := c.seriesTimeRange()
+	counts := make([]int, len(c.clients))
+	var wg sync.WaitGroup
+	for i, client := range c.clients {
+		wg.Add(1)
+		go func(i int, client *promql.Client) {
+			defer wg.Done()
+			// A failing server is ignored, as the query itself reports it.
+			if series, err := client.SeriesContext(ctx, []string{selector}, start, end); err == nil {
+				counts[i] = len(series)
+			}
+		}(i, client)
+	}
+	wg.Wait()
+
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	if total <= c.cardinalityWarn {
+		return ""
+	}
+	return fmt.Sprintf("%s matches ~%d series, which exceeds the threshold %d.", selector, total, c.cardinalityWarn)
+}
+
+// confirm asks the user for confirmation in the interactive mode.
+func (c *CLI) confirm(prompt string) bool {
+	c.rl.HistoryDisable()
+	defer c.rl.HistoryEnable()
+	c.rl.SetPrompt(prompt)
+	defer c.rl.SetPrompt(c.prompt())
+
+	line, err := c.rl.Readline()
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
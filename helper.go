@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultRateWindow = "5m"
+
+// runRateCommand runs `\rate [window] <selector>`, which wraps the selector of a counter with rate().
+func (c *CLI) runRateCommand(ctx context.Context, args string) error {
+	window, selector := defaultRateWindow, args
+	if first, rest, ok := strings.Cut(args, " "); ok && isDuration(first) {
+		window, selector = first, strings.TrimSpace(rest)
+	}
+	if selector == "" {
+		return errors.New(`usage: \rate [window] <selector>`)
+	}
+	if _, err := parseVectorSelector(selector); err != nil {
+		return err
+	}
+
+	c.runGeneratedQuery(ctx, fmt.Sprintf("rate(%s[%s])", selector, window))
+	return nil
+}
+
+// runQuantileCommand runs `\quantile <q> <window> <bucket-metric> [by <labels>]`,
+// which computes the quantile of the classic histogram.
+func (c *CLI) runQuantileCommand(ctx context.Context, args string) error {
+	usage := errors.New(`usage: \quantile <q> <window> <bucket-metric> [by <labels>]`)
+	fields, selector, ok := cutFields(args, 2)
+	if !ok {
+		return usage
+	}
+	q, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || q < 0 || q > 1 {
+		return fmt.Errorf("quantile must be between 0 and 1: %q", fields[0])
+	}
+	window := fields[1]
+	if !isDuration(window) {
+		return fmt.Errorf("invalid window: %q", window)
+	}
+
+	grouping := []string{"le"}
+	if i := strings.LastIndex(selector, " by "); i >= 0 {
+		for _, label := range strings.Split(selector[i+len(" by "):], ",") {
+			label = strings.TrimSpace(label)
+			if label == "" || label == "le" {
+				continue
+			}
+			grouping = append(grouping, label)
+		}
+		selector = strings.TrimSpace(selector[:i])
+	}
+	if _, err := parseVectorSelector(selector); err != nil {
+		return err
+	}
+
+	c.runGeneratedQuery(ctx, fmt.Sprintf("histogram_quantile(%s, sum(rate(%s[%s])) by (%s))",
+		fields[0], selector, window, strings.Join(grouping, ", ")))
+	return nil
+}
+
+// runTopkCommand runs `\topk <n> <query>` or `\bottomk <n> <query>`, which wraps the query with the aggregator.
+func (c *CLI) runTopkCommand(ctx context.Context, aggregator, args string) error {
+	n, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return fmt.Errorf(`usage: \%s <n> <query>`, aggregator)
+	}
+	if k, err := strconv.Atoi(n); err != nil || k <= 0 {
+		return fmt.Errorf("n must be a positive integer: %q", n)
+	}
+
+	c.runGeneratedQuery(ctx, fmt.Sprintf("%s(%s, %s)", aggregator, n, query))
+	return nil
+}
+
+// runGeneratedQuery echoes the query generated by a helper command, and runs it.
+func (c *CLI) runGeneratedQuery(ctx context.Context, query string) {
+	fmt.Fprintf(c.out, "%s\n", query)
+	c.runQuery(ctx, query)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+)
+
+// promqlPainter highlights the PromQL input and the bracket matching the one before the cursor.
+type promqlPainter struct{}
+
+func (p *promqlPainter) Paint(line []rune, pos int) []rune {
+	input := string(line)
+	if isCommand(strings.TrimSpace(input)) {
+		return line
+	}
+	tokens, err := lexPromQL(input)
+	if err != nil {
+		return line
+	}
+
+	// The opening bracket matching the closing one just before the cursor is highlighted.
+	matchPos := -1
+	if pos > 0 && pos <= len(line) && isClosingBracket(line[pos-1]) {
+		if i := matchingBracket(line, pos-1); i >= 0 {
+			matchPos = len(string(line[:i]))
+		}
+	}
+
+	var b strings.Builder
+	prev := 0
+	for i, t := range tokens {
+		if t.typ == tokenEOF {
+			break
+		}
+		end := t.pos + len(t.val)
+		b.WriteString(input[prev:t.pos])
+		if t.pos == matchPos {
+			b.WriteString(colorize(t.val, colorReverse))
+		} else if color := tokenColor(t, tokens[i+1]); color != "" {
+			b.WriteString(colorize(t.val, color))
+		} else {
+			b.WriteString(t.val)
+		}
+		prev = end
+	}
+	b.WriteString(input[prev:])
+	return []rune(b.String())
+}
+
+// tokenColor returns the color of the token, which depends on the next token to distinguish function names
+// and aggregation operators such as `sum by (job) (...)` from metric names.
+func tokenColor(t, next token) string {
+	switch t.typ {
+	case tokenKeyword:
+		return colorMagenta
+	case tokenIdentifier:
+		lowerNext := strings.ToLower(next.val)
+		if next.typ == tokenLeftParen || (next.typ == tokenKeyword && (lowerNext == "by" || lowerNext == "without")) {
+			return colorBlue
+		}
+	case tokenString:
+		return colorGreen
+	case tokenNumber, tokenDuration:
+		return colorYellow
+	case tokenComment:
+		return colorGray
+	}
+	return ""
+}
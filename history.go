@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const historyFile = "/tmp/promql_cli_history"
+
+// checkHistoryFile returns an error if the history file can't be opened for writing, e.g. on a read-only filesystem.
+// readline silently drops the history in that case, so it's checked beforehand to warn the user.
+func checkHistoryFile(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readHistoryFile returns the entries in the history file, from the oldest to the newest.
+func readHistoryFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// historySearch is the state of the prefix search of the history by Up and Down, like history-beginning-search
+// of zsh. The search starts when Up is pressed with some input, which becomes the prefix, and ends by any other key.
+type historySearch struct {
+	// line is the input being edited, which is tracked by the listener of readline.
+	line string
+
+	active  bool
+	prefix  string
+	entries []string
+	// index is the entry shown now, or len(entries) if the prefix itself is shown.
+	index int
+}
+
+// searchHistory returns the older entry starting with the prefix for Up, or the newer one for Down.
+// False is returned if readline should handle the key as usual.
+func (c *CLI) searchHistory(up bool) (string, bool) {
+	s := &c.historySearch
+	if !s.active {
+		path := c.rl.Config.HistoryFile
+		if !up || s.line == "" || path == "" {
+			return "", false
+		}
+		entries, err := readHistoryFile(path)
+		if err != nil {
+			return "", false
+		}
+		*s = historySearch{line: s.line, active: true, prefix: s.line, entries: entries, index: len(entries)}
+	}
+
+	step := 1
+	if up {
+		step = -1
+	}
+	for i := s.index + step; i >= 0 && i < len(s.entries); i += step {
+		// The same entry as the shown one is skipped, so that the repeated queries don't need several keys.
+		if entry := s.entries[i]; strings.HasPrefix(entry, s.prefix) && entry != s.line {
+			s.index = i
+			return entry, true
+		}
+	}
+	if up {
+		// No older entry, so the shown one is kept.
+		return s.line, true
+	}
+	s.index = len(s.entries)
+	return s.prefix, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckHistoryFile(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readOnly := filepath.Join(dir, "read-only")
+	if err := os.Mkdir(readOnly, 0o500); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		skipAsRoot bool
+		wantErr    bool
+	}{
+		{name: "new file", path: filepath.Join(dir, "history")},
+		{name: "missing directory", path: filepath.Join(dir, "missing", "history"), wantErr: true},
+		{name: "parent is a file", path: filepath.Join(notDir, "history"), wantErr: true},
+		// root can write to the read-only directory.
+		{name: "read-only directory", path: filepath.Join(readOnly, "history"), skipAsRoot: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.skipAsRoot && os.Geteuid() == 0 {
+				t.Skip("the permissions are bypassed by root")
+			}
+			if err := checkHistoryFile(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("checkHistoryFile(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("up\n\n  rate(x[5m])  \nsum(up)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readHistoryFile(path)
+	if err != nil {
+		t.Fatalf("readHistoryFile() error = %v", err)
+	}
+	if want := []string{"up", "rate(x[5m])", "sum(up)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readHistoryFile() = %q, want %q", got, want)
+	}
+
+	if _, err := readHistoryFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("readHistoryFile() of the missing file returned no error")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	// keyF5 is the input of F5 from xterm-compatible terminals.
+	keyF5 = "\033[15~"
+
+	// formatCycleRune is the rune which F5 is translated into, since readline drops the function keys.
+	// It's in the private use area, so it never appears in the queries typed by the user.
+	formatCycleRune = ''
+)
+
+// functionKeyReader translates F5 in the terminal input into formatCycleRune, so that the key can be handled
+// by the input filter of readline.
+type functionKeyReader struct {
+	r io.ReadCloser
+
+	// pending is the bytes which may be the beginning of the key.
+	pending []byte
+	out     []byte
+}
+
+func newFunctionKeyReader(r io.ReadCloser) *functionKeyReader {
+	return &functionKeyReader{r: r}
+}
+
+func (f *functionKeyReader) Read(b []byte) (int, error) {
+	for len(f.out) == 0 {
+		chunk := make([]byte, len(b))
+		n, err := f.r.Read(chunk)
+		for _, ch := range chunk[:n] {
+			f.filter(ch)
+		}
+		if err != nil {
+			f.out, f.pending = append(f.out, f.pending...), nil
+			if len(f.out) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	n := copy(b, f.out)
+	f.out = f.out[n:]
+	return n, nil
+}
+
+func (f *functionKeyReader) Close() error {
+	return f.r.Close()
+}
+
+func (f *functionKeyReader) filter(ch byte) {
+	f.pending = append(f.pending, ch)
+	switch pending := string(f.pending); {
+	case pending == keyF5:
+		f.out = append(f.out, string(formatCycleRune)...)
+		f.pending = nil
+	case strings.HasPrefix(keyF5, pending):
+		// Wait for the following bytes to tell if it's the key.
+	default:
+		// Other input including the escape sequences of the other keys. The last ESC may begin the key.
+		var last []byte
+		if ch == '\033' {
+			f.pending, last = f.pending[:len(f.pending)-1], []byte{ch}
+		}
+		f.out = append(f.out, f.pending...)
+		f.pending = last
+	}
+}
+
+// filterInputRune handles the keys translated by functionKeyReader and Up and Down for the prefix search of
+// the history, and passes the others to readline.
+func (c *CLI) filterInputRune(r rune) (rune, bool) {
+	switch r {
+	case formatCycleRune:
+		c.cycleFormat()
+		return r, false
+	case readline.CharPrev, readline.CharNext:
+		line, ok := c.searchHistory(r == readline.CharPrev)
+		if !ok {
+			return r, true
+		}
+		c.rl.Operation.SetBuffer(line)
+		c.historySearch.line = line
+		return r, false
+	}
+	c.historySearch.active = false
+	return r, true
+}
+
+// cycleFormat switches to the next output format and renders the last result again, bound to F5.
+func (c *CLI) cycleFormat() {
+	for i, format := range outputFormats {
+		if format == c.format {
+			c.setFormat(outputFormats[(i+1)%len(outputFormats)])
+			break
+		}
+	}
+
+	c.rl.Clean()
+	fmt.Fprintf(c.out, "Output format is %s\n", c.format)
+	if c.lastResponse == nil {
+		fmt.Fprintln(c.out)
+		return
+	}
+	// The table browser can't read the terminal while readline is reading it.
+	tui := c.tui
+	c.tui = false
+	c.PrintResult(c.lastResponse, "")
+	c.tui = tui
+}
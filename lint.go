@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const (
+	lintWarning = "warning"
+	lintInfo    = "info"
+
+	// minRateRangeScrapes is the recommended minimum number of scrape intervals in the range of rate() and the like,
+	// so that the range has enough samples even if some scrapes fail.
+	minRateRangeScrapes = 4
+)
+
+// counterFunctions are the functions which can take counters as they are.
+var counterFunctions = map[string]bool{
+	"rate": true, "irate": true, "increase": true, "resets": true, "changes": true,
+	"absent": true, "absent_over_time": true, "present_over_time": true, "count_over_time": true,
+	"count": true, "group": true, "timestamp": true,
+}
+
+// rateFunctions are the functions which need enough samples in the range to calculate the result.
+var rateFunctions = map[string]bool{
+	"rate": true, "increase": true, "delta": true, "deriv": true, "predict_linear": true,
+}
+
+// groupingKeywords are followed by the list of label names rather than an expression.
+var groupingKeywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+}
+
+var comparisonOperators = map[string]bool{
+	"==": true, "!=": true, ">": true, "<": true, ">=": true, "<=": true,
+}
+
+// lintFinding is an anti-pattern found in the query. Pos is the byte offset in the query.
+type lintFinding struct {
+	Severity string
+	Pos      int
+	Message  string
+}
+
+// runLintCommand runs `\lint <query>`, which reports the common anti-patterns in the query.
+// The query isn't run, and only the types of the counters are looked up by the Metadata API.
+func (c *CLI) runLintCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \lint <query>`)
+	}
+
+	types := make(map[string]string)
+	metricType := func(name string) string {
+		if t, ok := types[name]; ok {
+			return t
+		}
+		// The Metadata API is optional, and the type is guessed from the name if it's not available.
+		var t string
+		if !c.remoteRead {
+			if metadata, err := c.client.MetadataContext(ctx, name); err == nil && len(metadata) > 0 {
+				t = metadata[0].Type
+			}
+		}
+		types[name] = t
+		return t
+	}
+	findings, err := lintQuery(args, metricType)
+	if err != nil {
+		return err
+	}
+
+	table := promql.Table{Header: []string{"severity", "position", "message"}}
+	for _, f := range findings {
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{f.Severity, strconv.Itoa(f.Pos + 1), f.Message}})
+	}
+	summary := fmt.Sprintf("%d findings", len(findings))
+	if len(findings) == 0 {
+		summary = "No findings"
+	}
+	c.printTable(&table, summary)
+	return nil
+}
+
+// lintFrame is a pair of parentheses the walker is in.
+type lintFrame struct {
+	// function is the name of the function or the aggregation the parentheses belong to, or empty for grouping.
+	function string
+	// labels is true for the list of label names such as `by (job)`.
+	labels bool
+}
+
+// lintQuery walks the tokens of the query and returns the findings sorted by position. The metricType returns
+// the type of the metric such as "counter" if known, or empty.
+func lintQuery(query string, metricType func(string) string) ([]lintFinding, error) {
+	var tokens []token
+	all, err := lexPromQL(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		if t.typ != tokenComment {
+			tokens = append(tokens, t)
+		}
+	}
+
+	var (
+		findings []lintFinding
+		stack    []lintFrame
+		closed   lintFrame
+		inBraces bool
+	)
+	inFunction := func() bool {
+		for _, f := range stack {
+			if f.function != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for i, t := range tokens {
+		var prev, next token
+		if i > 0 {
+			prev = tokens[i-1]
+		}
+		if t.typ != tokenEOF {
+			next = tokens[i+1]
+		}
+
+		switch t.typ {
+		case tokenLeftParen:
+			frame := lintFrame{}
+			switch {
+			case prev.typ == tokenIdentifier:
+				frame.function = prev.val
+			case prev.typ == tokenKeyword && groupingKeywords[strings.ToLower(prev.val)]:
+				frame.labels = true
+				// The aggregation such as `sum by (job) (...)` is remembered for the following parentheses.
+				if i >= 2 && tokens[i-2].typ == tokenIdentifier {
+					frame.function = tokens[i-2].val
+				}
+			case prev.typ == tokenRightParen && closed.labels:
+				frame.function = closed.function
+			}
+			stack = append(stack, frame)
+		case tokenRightParen:
+			if len(stack) > 0 {
+				closed = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case tokenLeftBrace:
+			inBraces = true
+		case tokenRightBrace:
+			inBraces = false
+		case tokenIdentifier:
+			// Skip the functions, the aggregations such as `sum by (job)`, and the label names.
+			isAggregation := next.typ == tokenKeyword && groupingKeywords[strings.ToLower(next.val)]
+			if inBraces || next.typ == tokenLeftParen || isAggregation || len(stack) > 0 && stack[len(stack)-1].labels {
+				continue
+			}
+			if strings.EqualFold(t.val, "inf") || strings.EqualFold(t.val, "nan") {
+				continue
+			}
+			if !isCounter(t.val, metricType(t.val)) {
+				continue
+			}
+			counted := false
+			for _, f := range stack {
+				if counterFunctions[f.function] {
+					counted = true
+				}
+			}
+			if !counted {
+				findings = append(findings, lintFinding{Severity: lintWarning, Pos: t.pos,
+					Message: fmt.Sprintf("%s is a counter, which should be used with rate() or increase()", t.val)})
+			}
+		case tokenLeftBracket:
+			// Subqueries such as [1h:5m] are not range selectors.
+			if next.typ != tokenDuration || tokens[i+2].typ != tokenRightBracket || len(stack) == 0 {
+				continue
+			}
+			function := stack[len(stack)-1].function
+			d, err := parsePromDuration(next.val)
+			if err != nil || !rateFunctions[function] || d >= minRateRangeScrapes*assumedScrapeInterval {
+				continue
+			}
+			findings = append(findings, lintFinding{Severity: lintWarning, Pos: t.pos,
+				Message: fmt.Sprintf("the range [%s] of %s() is shorter than %d scrape intervals (%s assumed), which may have too few samples",
+					next.val, function, minRateRangeScrapes, assumedScrapeInterval)})
+		case tokenOperator:
+			switch {
+			case inBraces && (t.val == "=~" || t.val == "!~"):
+				if next.typ != tokenString || strings.ContainsAny(unquote(next.val), `.+*?()[]{}|^$\`) {
+					continue
+				}
+				exact := map[string]string{"=~": "=", "!~": "!="}[t.val]
+				findings = append(findings, lintFinding{Severity: lintInfo, Pos: t.pos,
+					Message: fmt.Sprintf("the regex matcher %s%s has no special characters, and can be the exact matcher %s%s", t.val, next.val, exact, next.val)})
+			case !inBraces && comparisonOperators[t.val]:
+				if next.typ == tokenKeyword && strings.EqualFold(next.val, "bool") || !inFunction() {
+					continue
+				}
+				findings = append(findings, lintFinding{Severity: lintInfo, Pos: t.pos,
+					Message: fmt.Sprintf("the comparison %s without bool filters the series instead of returning 0 or 1", t.val)})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Pos < findings[j].Pos })
+	return findings, nil
+}
+
+// isCounter returns true if the metric is a counter by the type, or by the name if the type is unknown.
+// The series of histograms and summaries such as _bucket and _count are counters as well.
+func isCounter(name, typ string) bool {
+	switch typ {
+	case "counter":
+		return true
+	case "histogram", "summary", "gaugehistogram":
+		return strings.HasSuffix(name, "_bucket") || strings.HasSuffix(name, "_count") || strings.HasSuffix(name, "_sum")
+	case "":
+		for _, suffix := range []string{"_total", "_bucket", "_count", "_sum"} {
+			if strings.HasSuffix(name, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
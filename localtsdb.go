@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localEngine evaluates the queries for -tsdb-path, returning the result type and the result in JSON.
+type localEngine interface {
+	Query(ctx context.Context, query string, ts time.Time, lookbackDelta time.Duration) (string, json.RawMessage, error)
+	QueryRange(ctx context.Context, query string, start, end time.Time, step, lookbackDelta time.Duration) (string, json.RawMessage, error)
+}
+
+// openLocalTSDB opens the TSDB in the directory, and returns its file URL and the transport serving the queries.
+func openLocalTSDB(path string) (string, http.RoundTripper, io.Closer, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	engine, db, err := openLocalEngine(dir)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open the TSDB: %w", err)
+	}
+	return (&url.URL{Scheme: "file", Path: dir}).String(), &localQueryTransport{engine: engine}, db, nil
+}
+
+// localQueryTransport serves the Query API and the Range Query API by the local engine.
+type localQueryTransport struct {
+	engine localEngine
+}
+
+// localAPIResponse is the response of the Prometheus HTTP API written by localQueryTransport.
+type localAPIResponse struct {
+	Status    string          `json:"status"`
+	Data      *localQueryData `json:"data,omitempty"`
+	ErrorType string          `json:"errorType,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type localQueryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+func (t *localQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params := req.URL.Query()
+	ctx := req.Context()
+	var lookbackDelta time.Duration
+	if s := params.Get("lookback_delta"); s != "" {
+		d, err := parsePromDuration(s)
+		if err != nil {
+			return localErrorResponse(req, http.StatusBadRequest, "bad_data", err), nil
+		}
+		lookbackDelta = d
+	}
+	if s := params.Get("timeout"); s != "" {
+		d, err := parsePromDuration(s)
+		if err != nil {
+			return localErrorResponse(req, http.StatusBadRequest, "bad_data", err), nil
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	var resultType string
+	var result json.RawMessage
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/api/v1/query"):
+		ts := time.Now()
+		if s := params.Get("time"); s != "" {
+			var err error
+			if ts, err = parseTime(s); err != nil {
+				return localErrorResponse(req, http.StatusBadRequest, "bad_data", err), nil
+			}
+		}
+		var err error
+		if resultType, result, err = t.engine.Query(ctx, params.Get("query"), ts, lookbackDelta); err != nil {
+			return localErrorResponse(req, http.StatusUnprocessableEntity, "execution", err), nil
+		}
+	case strings.HasSuffix(req.URL.Path, "/api/v1/query_range"):
+		start, err := parseTime(params.Get("start"))
+		if err != nil {
+			return localErrorResponse(req, http.StatusBadRequest, "bad_data", err), nil
+		}
+		end, err := parseTime(params.Get("end"))
+		if err != nil {
+			return localErrorResponse(req, http.StatusBadRequest, "bad_data", err), nil
+		}
+		step, err := strconv.ParseFloat(params.Get("step"), 64)
+		if err != nil || step <= 0 {
+			return localErrorResponse(req, http.StatusBadRequest, "bad_data", fmt.Errorf("invalid step: %q", params.Get("step"))), nil
+		}
+		stepDuration := time.Duration(step * float64(time.Second))
+		if resultType, result, err = t.engine.QueryRange(ctx, params.Get("query"), start, end, stepDuration, lookbackDelta); err != nil {
+			return localErrorResponse(req, http.StatusUnprocessableEntity, "execution", err), nil
+		}
+	default:
+		return localErrorResponse(req, http.StatusNotFound, "not_found", fmt.Errorf("%s is not available with -tsdb-path", req.URL.Path)), nil
+	}
+	return localResponse(req, http.StatusOK, localAPIResponse{
+		Status: "success",
+		Data:   &localQueryData{ResultType: resultType, Result: result},
+	}), nil
+}
+
+func localErrorResponse(req *http.Request, status int, errorType string, err error) *http.Response {
+	return localResponse(req, status, localAPIResponse{Status: "error", ErrorType: errorType, Error: err.Error()})
+}
+
+func localResponse(req *http.Request, status int, body localAPIResponse) *http.Response {
+	b, err := json.Marshal(body)
+	if err != nil {
+		// Unreachable since the result is already JSON.
+		b = []byte(`{"status":"error","errorType":"internal","error":"failed to encode the result"}`)
+		status = http.StatusInternalServerError
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,65 @@
+//go:build tsdb
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	promqlengine "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+// tsdbEngine evaluates the queries on the TSDB in a directory by the query engine of Prometheus.
+type tsdbEngine struct {
+	db     *tsdb.DBReadOnly
+	engine *promqlengine.Engine
+}
+
+// openLocalEngine opens the TSDB in the directory read-only, such as a snapshot.
+func openLocalEngine(dir string) (localEngine, io.Closer, error) {
+	db, err := tsdb.OpenDBReadOnly(dir, os.TempDir(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	engine := promqlengine.NewEngine(promqlengine.EngineOpts{
+		MaxSamples:           50000000,
+		Timeout:              2 * time.Minute,
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+	})
+	return &tsdbEngine{db: db, engine: engine}, db, nil
+}
+
+func (e *tsdbEngine) Query(ctx context.Context, query string, ts time.Time, lookbackDelta time.Duration) (string, json.RawMessage, error) {
+	q, err := e.engine.NewInstantQuery(ctx, e.db, promqlengine.NewPrometheusQueryOpts(false, lookbackDelta), query, ts)
+	if err != nil {
+		return "", nil, err
+	}
+	return execLocalQuery(ctx, q)
+}
+
+func (e *tsdbEngine) QueryRange(ctx context.Context, query string, start, end time.Time, step, lookbackDelta time.Duration) (string, json.RawMessage, error) {
+	q, err := e.engine.NewRangeQuery(ctx, e.db, promqlengine.NewPrometheusQueryOpts(false, lookbackDelta), query, start, end, step)
+	if err != nil {
+		return "", nil, err
+	}
+	return execLocalQuery(ctx, q)
+}
+
+// execLocalQuery runs the query and encodes the result before closing the query.
+func execLocalQuery(ctx context.Context, q promqlengine.Query) (string, json.RawMessage, error) {
+	defer q.Close()
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return "", nil, res.Err
+	}
+	b, err := json.Marshal(res.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(res.Value.Type()), b, nil
+}
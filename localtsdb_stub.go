@@ -0,0 +1,13 @@
+//go:build !tsdb
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// openLocalEngine is not available without the tsdb build tag, which links the TSDB and the query engine of Prometheus.
+func openLocalEngine(dir string) (localEngine, io.Closer, error) {
+	return nil, nil, errors.New("-tsdb-path is not supported by this binary, build it with -tags tsdb")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// fakeLocalEngine records the arguments, and returns the canned result or error.
+type fakeLocalEngine struct {
+	resultType string
+	result     string
+	err        error
+
+	query         string
+	start, end    time.Time
+	step          time.Duration
+	lookbackDelta time.Duration
+}
+
+func (e *fakeLocalEngine) Query(ctx context.Context, query string, ts time.Time, lookbackDelta time.Duration) (string, json.RawMessage, error) {
+	e.query, e.start, e.lookbackDelta = query, ts, lookbackDelta
+	return e.resultType, json.RawMessage(e.result), e.err
+}
+
+func (e *fakeLocalEngine) QueryRange(ctx context.Context, query string, start, end time.Time, step, lookbackDelta time.Duration) (string, json.RawMessage, error) {
+	e.query, e.start, e.end, e.step, e.lookbackDelta = query, start, end, step, lookbackDelta
+	return e.resultType, json.RawMessage(e.result), e.err
+}
+
+func newLocalTestClient(t *testing.T, engine localEngine) *promql.Client {
+	t.Helper()
+	client, err := promql.NewClient(context.Background(), "file:///data/snapshot", "", "", promql.DefaultTransportOptions(), promql.GCPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetHTTPClient(&http.Client{Transport: &localQueryTransport{engine: engine}})
+	return client
+}
+
+func TestLocalQueryTransport_Query(t *testing.T) {
+	engine := &fakeLocalEngine{resultType: "vector", result: `[{"metric":{"job":"a"},"value":[1719292597.171,"1"]}]`}
+	client := newLocalTestClient(t, engine)
+
+	ts := time.UnixMilli(1719292597171)
+	resp, err := client.QueryContext(context.Background(), "up", promql.QueryOptions{Time: ts, LookbackDelta: "1d"})
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	want := promql.ResultVector{{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.171, "1"}}}
+	if !reflect.DeepEqual(resp.Data.Result, want) {
+		t.Errorf("QueryContext() result = %#v, want %#v", resp.Data.Result, want)
+	}
+	if engine.query != "up" || !engine.start.Equal(ts) || engine.lookbackDelta != 24*time.Hour {
+		t.Errorf("engine got query %q, time %v, lookback delta %v", engine.query, engine.start, engine.lookbackDelta)
+	}
+}
+
+func TestLocalQueryTransport_QueryRange(t *testing.T) {
+	engine := &fakeLocalEngine{resultType: "matrix", result: `[{"metric":{"job":"a"},"values":[[1719292597,"1"]]}]`}
+	client := newLocalTestClient(t, engine)
+
+	start, end := time.Unix(1719289000, 0), time.Unix(1719292600, 0)
+	resp, err := client.QueryRangeContext(context.Background(), "up", start, end, 15*time.Second)
+	if err != nil {
+		t.Fatalf("QueryRangeContext() error = %v", err)
+	}
+	want := promql.ResultMatrix{{Metric: map[string]string{"job": "a"}, Points: [][]any{{1719292597.0, "1"}}}}
+	if !reflect.DeepEqual(resp.Data.Result, want) {
+		t.Errorf("QueryRangeContext() result = %#v, want %#v", resp.Data.Result, want)
+	}
+	if !engine.start.Equal(start) || !engine.end.Equal(end) || engine.step != 15*time.Second {
+		t.Errorf("engine got start %v, end %v, step %v", engine.start, engine.end, engine.step)
+	}
+}
+
+func TestLocalQueryTransport_Errors(t *testing.T) {
+	client := newLocalTestClient(t, &fakeLocalEngine{err: errors.New(`parse error: unexpected "}"`)})
+	if _, err := client.QueryContext(context.Background(), "up}", promql.QueryOptions{}); err == nil || !strings.Contains(err.Error(), `parse error: unexpected "}"`) {
+		t.Errorf("QueryContext() error = %v, want the error of the engine", err)
+	}
+	if _, err := client.QueryContext(context.Background(), "up", promql.QueryOptions{LookbackDelta: "5mm"}); err == nil || !strings.Contains(err.Error(), "invalid duration") {
+		t.Errorf("QueryContext() error = %v, want the invalid lookback delta", err)
+	}
+	// Only the query APIs are served.
+	if _, err := client.SeriesContext(context.Background(), []string{"up"}, time.Now().Add(-time.Hour), time.Now()); err == nil || !strings.Contains(err.Error(), "not available with -tsdb-path") {
+		t.Errorf("SeriesContext() error = %v, want the unavailable API", err)
+	}
+}
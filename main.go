@@ -1,24 +1,162 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
 )
 
 func main() {
-	var url, project, headers string
+	config := Config{Transport: promql.DefaultTransportOptions()}
+	var timing timingFlag
+	var dedup, partialResponse queryParamFlag
+	var query, queryFile string
+	var replay string
+	var noSaveSettings bool
+	var batch bool
+	var concurrency int
+	var logFormat, logLevel string
 
-	flag.StringVar(&url, "url", "http://localhost:9090", "The URL for the Prometheus server")
-	flag.StringVar(&project, "project", "", "Google Cloud Project ID for Cloud Monitoring")
-	flag.StringVar(&headers, "headers", "", "Additional request headers (comma separated) for Query API")
+	flag.StringVar(&config.URL, "url", "http://localhost:9090", "The URL for the Prometheus server. Multiple servers can be given as comma separated URLs, and the results are merged with the label given by -origin-label")
+	flag.StringVar(&config.OriginLabel, "origin-label", defaultOriginLabel, "The label added to each series with the URL of the server it comes from when multiple servers are given by -url")
+	flag.StringVar(&config.RemoteReadURL, "remote-read-url", "", "Read selectors such as up{job=\"x\"}[1h] by the remote read protocol from the URL instead of the query API (-url is ignored)")
+	flag.StringVar(&config.TSDBPath, "tsdb-path", "", "Evaluate the queries locally on the TSDB in the directory, such as a snapshot, instead of querying the server (-url is ignored). Only for the binary built with -tags tsdb")
+	flag.StringVar(&config.Project, "project", "", "Google Cloud Project ID for Cloud Monitoring")
+	flag.StringVar(&config.GCP.QuotaProject, "gcp-quota-project", "", "Google Cloud Project ID billed for the Cloud Monitoring API requests")
+	flag.StringVar(&config.GCP.ImpersonateServiceAccount, "gcp-impersonate-sa", "", "Email of the service account to impersonate for Cloud Monitoring")
+	flag.StringVar(&config.Headers, "headers", "", "Additional request headers (comma separated) for Query API")
+	flag.Var(&timing, "timing", "Show client-side query latency (-timing=detailed for DNS/connect/TTFB breakdown)")
+	flag.StringVar(&config.LookbackDelta, "lookback-delta", "", "Override the lookback delta of the server for queries (e.g. 10m), sent as the lookback_delta parameter. Same as \\lookback")
+	flag.StringVar(&config.ServerTimeout, "server-timeout", "", "Timeout of the query evaluation on the server (e.g. 10s), sent as the timeout parameter. The server default is used if not given")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "Print the request URL and the equivalent curl command instead of sending the query")
+	flag.BoolVar(&config.ShowSecrets, "show-secrets", false, "Show sensitive header values in the -dry-run output")
+	flag.DurationVar(&config.CacheTTL, "cache-ttl", 0, "Cache query results for the given duration (e.g. 5m). Queries evaluated at the current time are not cached")
+	flag.IntVar(&config.MaxWidth, "max-width", 0, "Truncate label values wider than the given number of columns with ellipsis, where wide characters such as CJK take two (0 for no truncation). Timestamps and sample values are never truncated")
+	flag.StringVar(&config.Format, "format", formatTable, "Output format (table, csv, tsv, markdown, expanded, raw-json)")
+	flag.BoolVar(&config.TUI, "tui", false, "Show table results in a scrollable browser with search and sort")
+	flag.BoolVar(&config.PrettyJSON, "pretty-json", false, "Indent the raw-json output even if it's not written to a terminal")
+	flag.StringVar(&config.Align, "align", alignAuto, "Alignment of the table columns: auto (right-align numeric columns such as value) or left")
+	flag.StringVar(&config.MatrixLayout, "matrix-layout", matrixLayoutPoint, "Layout of range vector results: point (one row per point) or series (one row per series with comma separated values)")
+	flag.BoolVar(&config.Summary, "summary", false, "Render range vector results as one row per series with min, avg, max and last values")
+	flag.StringVar(&config.TimestampFormat, "timestamp", timestampFormatRFC3339, "Format of the timestamp column: rfc3339, epoch (Unix seconds), or both")
+	flag.BoolVar(&config.ShowType, "show-type", false, "Show the result type (scalar, string, vector or matrix) above the result. The raw-json output always has it as resultType")
+	flag.BoolVar(&config.NoHeader, "no-header", false, "Don't output the header row")
+	flag.BoolVar(&config.BoolMarkers, "bool", false, "Render the values as ✓/✗ when all of them are 0 or 1, such as the result of comparisons with the bool modifier")
+	flag.BoolVar(&config.DurationMetrics, "duration-metrics", false, "Render the values of the metrics whose names end with _seconds as durations such as 1h2m3s")
+	flag.BoolVar(&config.NoExponent, "no-exponent", false, "Render the values in the scientific notation such as 1.5e+07 in plain decimals such as 15000000")
+	flag.StringVar(&config.DropLabels, "drop-labels", "", "Labels (comma separated) to hide from the output")
+	flag.StringVar(&config.TrimPrefix, "trim-prefix", "", "Strip the common prefix (e.g. myapp_) from the metric names in the output")
+	flag.StringVar(&config.DedupBy, "dedup-by", "", "Collapse the series of a vector or matrix result with the same labels except the given label, such as a replica label added by federation, keeping the one with the most recent sample (on a tie, the smallest value of the label)")
+	flag.BoolVar(&config.NoSpinner, "no-spinner", false, "Don't show the progress spinner while running a query")
+	flag.IntVar(&config.Transport.MaxIdleConns, "max-idle-conns", config.Transport.MaxIdleConns, "Maximum number of idle (keep-alive) connections to the server, which are reused by the queries run in parallel")
+	flag.DurationVar(&config.Transport.IdleConnTimeout, "idle-timeout", config.Transport.IdleConnTimeout, "How long an idle (keep-alive) connection remains open")
+	flag.BoolVar(&config.Transport.DisableCompression, "no-compression", false, "Don't request gzip-compressed responses")
+	flag.BoolVar(&config.Transport.DisableHTTP2, "disable-http2", false, "Disable HTTP/2")
+	flag.IntVar(&config.CardinalityWarn, "cardinality-warn", defaultCardinalityWarn, "Ask for confirmation before running a bare selector matching more series than this (0 to skip the check). In the non-interactive mode, the check is done only if given, and refuses the query with -fail-fast")
+	flag.IntVar(&config.MaxSamplesWarn, "max-samples-warn", defaultMaxSamplesWarn, "Ask for confirmation before running a query estimated to read more samples per series than this, such as one with a [30d] range (0 to skip the check)")
+	flag.BoolVar(&config.ValueOnly, "value-only", false, "Print only the value of -query, -query-file or each -batch query, which must be a scalar or a vector with one series, such as for X=$(promql-cli -query 'scalar(...)' -value-only). The value is printed as returned by the server, without the formatting such as -no-exponent")
+	flag.BoolVar(&config.FailOnEmpty, "fail-on-empty", false, "Exit with the code 2 if the result of -query or -query-file, or of any -batch query, is empty")
+	flag.BoolVar(&config.FailFast, "fail-fast", false, "Refuse to run a query that needs confirmation in the non-interactive mode")
+	flag.Var(&dedup, "dedup", "Set the dedup parameter of Thanos Querier (-dedup=false to disable deduplication). Ignored by Prometheus")
+	flag.Var(&partialResponse, "partial-response", "Set the partial_response parameter of Thanos Querier. Ignored by Prometheus")
+	flag.BoolVar(&noSaveSettings, "no-save-settings", false, "Don't save the session settings (format, max width, etc.) on exit")
+	flag.StringVar(&query, "query", "", "Run the given query and exit (non-interactive mode)")
+	flag.StringVar(&queryFile, "query-file", "", "Run the query read from the file and exit (non-interactive mode)")
+	flag.StringVar(&config.RecordDir, "record", "", "Record the response of each query to a timestamped file in the directory, with the query in the .meta.json file, for -replay")
+	flag.StringVar(&replay, "replay", "", "Render the Query API response saved in the file, such as the one recorded by -record, without querying the server and exit")
+	flag.BoolVar(&batch, "batch", false, "Run the queries read from stdin, one per line, and exit (non-interactive mode)")
+	flag.IntVar(&concurrency, "concurrency", 1, "Number of queries run in parallel in the -batch mode. Outputs are in the order of the input")
+	flag.StringVar(&logFormat, "log-format", logFormatText, "Format of the diagnostic logs written to stderr (text, json)")
+	flag.StringVar(&logLevel, "log-level", "warn", "Level of the diagnostic logs (debug, info, warn, error). Requests are logged at debug with their X-Request-Id, and retries at info")
 	flag.Parse()
 
-	cli, err := NewCLI(url, project, headers, os.Stdin, os.Stdout)
+	logger, err := newLogger(os.Stderr, logFormat, logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	// SetDefault redirects the log package to the logger, but the fatal errors below should always be shown as before.
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+	config.Timing = string(timing)
+	config.Dedup = string(dedup)
+	config.PartialResponse = string(partialResponse)
+	config.SaveSettings = !noSaveSettings
+
+	if queryFile != "" {
+		if query != "" {
+			log.Fatal("-query and -query-file cannot be used together")
+		}
+		b, err := os.ReadFile(queryFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		query = strings.TrimRight(string(b), "\r\n")
+		if strings.TrimSpace(query) == "" {
+			log.Fatalf("%s is empty", queryFile)
+		}
+	}
+
+	if replay != "" && (batch || query != "") {
+		log.Fatal("-replay cannot be used with -batch, -query or -query-file")
+	}
+	if batch && query != "" {
+		log.Fatal("-batch cannot be used with -query or -query-file")
+	}
+	if config.ValueOnly && query == "" && !batch {
+		log.Fatal("-value-only can be used only with -query, -query-file or -batch")
+	}
+	if config.ServerTimeout != "" && !isDuration(config.ServerTimeout) {
+		log.Fatalf("invalid -server-timeout: %q, expected a duration such as 10s", config.ServerTimeout)
+	}
+	if concurrency < 1 {
+		log.Fatal("-concurrency must be at least 1")
+	}
+	if config.LookbackDelta != "" && !isDuration(config.LookbackDelta) {
+		log.Fatalf("invalid -lookback-delta: %q, expected a duration such as 10m", config.LookbackDelta)
+	}
+
+	explicitFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+	if replay == "" && !batch && query == "" {
+		// The saved settings are restored only in the interactive mode, so that the output of scripts doesn't depend
+		// on the last interactive session.
+		if settings, err := loadSettings(); err != nil {
+			log.Printf("failed to load the saved settings: %v", err)
+		} else if settings != nil {
+			applySettings(&config, settings, explicitFlags)
+		}
+	} else if !explicitFlags["cardinality-warn"] {
+		// The cardinality check costs a Series API request before each query, which is worth it only when
+		// the user can answer the confirmation, so it's done in the non-interactive mode only if asked.
+		config.CardinalityWarn = 0
+	}
+
+	cli, err := NewCLI(&config, os.Stdin, os.Stdout, os.Stderr)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	exitCode := cli.RunInteractive()
+	ctx := context.Background()
+	var exitCode int
+	if replay != "" {
+		exitCode = cli.RunReplay(replay)
+	} else if batch {
+		exitCode = cli.RunBatch(ctx, concurrency)
+	} else if query != "" {
+		exitCode = cli.RunOnce(ctx, query)
+	} else {
+		exitCode = cli.RunInteractive(ctx)
+	}
+	if err := cli.Close(); err != nil {
+		log.Printf("failed to close: %v", err)
+	}
 	os.Exit(exitCode)
 }
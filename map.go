@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// mapFunctions are the functions available in the expression of `\map`, named after the PromQL ones.
+var mapFunctions = map[string]func(float64) float64{
+	"abs": math.Abs, "ceil": math.Ceil, "floor": math.Floor, "round": math.Round,
+	"sqrt": math.Sqrt, "exp": math.Exp, "ln": math.Log, "log2": math.Log2, "log10": math.Log10,
+}
+
+// runMapCommand runs `\map <expr>`, which applies the arithmetic expression of x to each value of the last result.
+func (c *CLI) runMapCommand(args string) error {
+	if args == "" {
+		return errors.New(`usage: \map <expr>`)
+	}
+	f, err := parseMapExpr(args)
+	if err != nil {
+		return err
+	}
+	if c.lastResponse == nil {
+		fmt.Fprintf(c.out, "no previous result\n\n")
+		return nil
+	}
+
+	mapped, err := mapResponse(c.lastResponse, f)
+	if err != nil {
+		return err
+	}
+	c.lastResponse = mapped
+	c.PrintResult(mapped, "")
+	return nil
+}
+
+// mapResponse returns the copy of the response with the function applied to each value. The raw body is dropped
+// so that the mapped values are written by the raw-json format as well.
+func mapResponse(resp *promql.QueryResponse, f func(float64) float64) (*promql.QueryResponse, error) {
+	mapValue := func(point []any) []any {
+		value, ok := point[1].(string)
+		if !ok {
+			return point
+		}
+		v, ok := parseSampleValue(value)
+		if !ok {
+			return point
+		}
+		return []any{point[0], formatFloat(f(v))}
+	}
+
+	var result any
+	switch r := resp.Data.Result.(type) {
+	case promql.ResultScalar:
+		result = promql.ResultScalar(mapValue(r))
+	case promql.ResultVector:
+		vector := make(promql.ResultVector, len(r))
+		for i, timeseries := range r {
+			vector[i] = promql.VectorTimeSeries{Metric: timeseries.Metric, Point: mapValue(timeseries.Point)}
+		}
+		result = vector
+	case promql.ResultMatrix:
+		matrix := make(promql.ResultMatrix, len(r))
+		for i, timeseries := range r {
+			points := make([][]any, len(timeseries.Points))
+			for j, point := range timeseries.Points {
+				points[j] = mapValue(point)
+			}
+			matrix[i] = promql.MatrixTimeSeries{Metric: timeseries.Metric, Points: points}
+		}
+		result = matrix
+	default:
+		return resp, nil
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	copied := *resp
+	copied.Data.Result = result
+	copied.Data.ResultRaw = raw
+	copied.Body = nil
+	copied.Cached = false
+	return &copied, nil
+}
+
+// parseMapExpr parses the arithmetic expression of x with +, -, *, /, % and ^, the parentheses, and the functions
+// in mapFunctions, and returns the function evaluating it.
+func parseMapExpr(expr string) (func(float64) float64, error) {
+	p := &mapExprParser{input: expr}
+	f, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos:], p.pos+1)
+	}
+	return f, nil
+}
+
+// mapExprParser is a recursive descent parser of the expression of `\map`. Each parse method returns the function
+// evaluating the parsed part for the value of x.
+type mapExprParser struct {
+	input string
+	pos   int
+}
+
+func (p *mapExprParser) skipSpaces() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume skips the operator if it's the next, and returns true in that case.
+func (p *mapExprParser) consume(op byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseExpr parses the terms joined by + and -.
+func (p *mapExprParser) parseExpr() (func(float64) float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.consume('+'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(x float64) float64 { return l(x) + right(x) }
+		case p.consume('-'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			l := left
+			left = func(x float64) float64 { return l(x) - right(x) }
+		default:
+			return left, nil
+		}
+	}
+}
+
+// parseTerm parses the factors joined by *, / and %.
+func (p *mapExprParser) parseTerm() (func(float64) float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op func(a, b float64) float64
+		switch {
+		case p.consume('*'):
+			op = func(a, b float64) float64 { return a * b }
+		case p.consume('/'):
+			op = func(a, b float64) float64 { return a / b }
+		case p.consume('%'):
+			op = math.Mod
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(x float64) float64 { return op(l(x), right(x)) }
+	}
+}
+
+// parseUnary parses the factor with the optional sign. The sign binds looser than ^, so -x^2 is -(x^2).
+func (p *mapExprParser) parseUnary() (func(float64) float64, error) {
+	switch {
+	case p.consume('-'):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(x float64) float64 { return -operand(x) }, nil
+	case p.consume('+'):
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower parses the power, which is right associative.
+func (p *mapExprParser) parsePower() (func(float64) float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume('^') {
+		return base, nil
+	}
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(x float64) float64 { return math.Pow(base(x), exponent(x)) }, nil
+}
+
+// parsePrimary parses x, a number, a function call or a parenthesized expression.
+func (p *mapExprParser) parsePrimary() (func(float64) float64, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, errors.New("unexpected end of the expression")
+	}
+	if p.consume('(') {
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		return inner, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isMapWordByte(p.input[p.pos], p.pos > start && strings.ContainsRune("eE", rune(p.input[p.pos-1]))) {
+		p.pos++
+	}
+	word := p.input[start:p.pos]
+	switch {
+	case word == "":
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos:p.pos+1], p.pos+1)
+	case word == "x":
+		return func(x float64) float64 { return x }, nil
+	case mapFunctions[word] != nil:
+		fn := mapFunctions[word]
+		if !p.consume('(') {
+			return nil, fmt.Errorf("missing ( after %s", word)
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		return func(x float64) float64 { return fn(arg(x)) }, nil
+	}
+	v, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unknown word %q, expected x, a number or a function", word)
+	}
+	return func(float64) float64 { return v }, nil
+}
+
+// isMapWordByte returns true if the byte is a part of x, a number such as 1.5e-9, or a function name.
+// The sign is a part of the word only after the exponent marker.
+func isMapWordByte(b byte, afterExponent bool) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '.', b == '_':
+		return true
+	case b == '+' || b == '-':
+		return afterExponent
+	}
+	return false
+}
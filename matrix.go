@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// Layouts of range vector results.
+const (
+	// matrixLayoutPoint renders one row per point.
+	matrixLayoutPoint = "point"
+	// matrixLayoutSeries renders one row per series with the values of all points joined by comma.
+	matrixLayoutSeries = "series"
+)
+
+// buildSeriesLayoutTable builds the table which has one row per series. The values are in the order of time.
+func buildSeriesLayoutTable(matrix promql.ResultMatrix, labelNames []string, opts *TableOptions) *promql.Table {
+	table := promql.Table{}
+	table.Header = append(table.Header, labelNames...)
+	table.Header = append(table.Header, "values")
+
+	for _, timeseries := range matrix {
+		var row promql.Row
+		for _, labelName := range labelNames {
+			row.Columns = append(row.Columns, opts.labelValue(labelName, timeseries.Metric[labelName]))
+		}
+		values := make([]string, 0, len(timeseries.Points))
+		for _, point := range timeseries.Points {
+			values = append(values, opts.formatValue(timeseries.Metric, point[1].(string)))
+		}
+		row.Columns = append(row.Columns, strings.Join(values, ","))
+		table.Rows = append(table.Rows, row)
+	}
+	return &table
+}
+
+// buildSummaryTable builds the table which has one row per series with min, avg, max and last values of the points.
+// NaN values are excluded from min, avg and max. NaN is shown if there are no other values.
+func buildSummaryTable(matrix promql.ResultMatrix, labelNames []string, opts *TableOptions) *promql.Table {
+	table := promql.Table{}
+	table.Header = append(table.Header, labelNames...)
+	table.Header = append(table.Header, "min", "avg", "max", "last")
+
+	for _, timeseries := range matrix {
+		var row promql.Row
+		for _, labelName := range labelNames {
+			row.Columns = append(row.Columns, opts.labelValue(labelName, timeseries.Metric[labelName]))
+		}
+		for _, value := range summarizePoints(timeseries.Points) {
+			row.Columns = append(row.Columns, opts.formatValue(timeseries.Metric, value))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return &table
+}
+
+// summarizePoints returns min, avg, max and last values of the points.
+func summarizePoints(points [][]any) []string {
+	minValue, maxValue, sum, n := math.Inf(1), math.Inf(-1), 0.0, 0
+	for _, point := range points {
+		v, ok := parseSampleValue(point[1].(string))
+		if !ok || math.IsNaN(v) {
+			continue
+		}
+		minValue = math.Min(minValue, v)
+		maxValue = math.Max(maxValue, v)
+		sum += v
+		n++
+	}
+
+	var last string
+	if len(points) > 0 {
+		last = points[len(points)-1][1].(string)
+	}
+	if n == 0 {
+		return []string{"NaN", "NaN", "NaN", last}
+	}
+	return []string{formatFloat(minValue), formatFloat(sum / float64(n)), formatFloat(maxValue), last}
+}
+
+// formatFloat formats the value in the same way as Prometheus formats sample values in the API responses,
+// which is in the exponent notation for very small or large values such as 1e-07 and 1e+21.
+func formatFloat(v float64) string {
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return strconv.FormatFloat(v, format, -1, 64)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{v: 0, want: "0"},
+		{v: 1, want: "1"},
+		{v: -0.5, want: "-0.5"},
+		{v: 1719292597.171, want: "1719292597.171"},
+		{v: 1e7, want: "10000000"},
+		{v: 1e-6, want: "0.000001"},
+		{v: 1e-7, want: "1e-07"},
+		{v: -2.5e-10, want: "-2.5e-10"},
+		{v: 1e20, want: "100000000000000000000"},
+		{v: 1e21, want: "1e+21"},
+		{v: math.MaxFloat64, want: "1.7976931348623157e+308"},
+		{v: math.Inf(1), want: "+Inf"},
+		{v: math.Inf(-1), want: "-Inf"},
+		{v: math.NaN(), want: "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.v); got != tt.want {
+			t.Errorf("formatFloat(%v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
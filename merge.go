@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// defaultOriginLabel is the default label added to each time series to indicate which server it comes from
+// when querying multiple servers.
+const defaultOriginLabel = "cluster"
+
+// queryAll runs the query against all servers concurrently, and returns the merged response with the errors per server.
+// An error is returned only if the query failed on all servers.
+func (c *CLI) queryAll(ctx context.Context, q string, opts promql.QueryOptions) (*promql.QueryResponse, map[string]error, error) {
+	resps := make([]*promql.QueryResponse, len(c.clients))
+	errs := make([]error, len(c.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range c.clients {
+		wg.Add(1)
+		go func(i int, client *promql.Client) {
+			defer wg.Done()
+			resps[i], errs[i] = client.QueryContext(ctx, q, opts)
+		}(i, client)
+	}
+	wg.Wait()
+
+	var origins []string
+	var succeeded []*promql.QueryResponse
+	serverErrs := make(map[string]error)
+	for i, client := range c.clients {
+		if errs[i] != nil {
+			serverErrs[client.BaseURL()] = errs[i]
+			continue
+		}
+		origins = append(origins, client.BaseURL())
+		succeeded = append(succeeded, resps[i])
+	}
+	if len(succeeded) == 0 {
+		return nil, nil, fmt.Errorf("query failed on all servers: %w", errors.Join(errs...))
+	}
+
+	merged, err := mergeResponses(succeeded, origins, c.originLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merged, serverErrs, nil
+}
+
+// mergeResponses merges the responses into one, adding originLabel with the base URL of the server to each series.
+func mergeResponses(resps []*promql.QueryResponse, origins []string, originLabel string) (*promql.QueryResponse, error) {
+	var vector promql.ResultVector
+	var matrix promql.ResultMatrix
+	var warnings, infos []string
+	for i, resp := range resps {
+		origin := origins[i]
+		for _, warning := range resp.Warnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", origin, warning))
+		}
+		for _, info := range resp.Infos {
+			infos = append(infos, fmt.Sprintf("%s: %s", origin, info))
+		}
+		switch result := resp.Data.Result.(type) {
+		case promql.ResultScalar:
+			vector = append(vector, promql.VectorTimeSeries{Metric: map[string]string{originLabel: origin}, Point: result})
+		case promql.ResultString:
+			vector = append(vector, promql.VectorTimeSeries{Metric: map[string]string{originLabel: origin}, Point: result})
+		case promql.ResultVector:
+			for _, timeseries := range result {
+				timeseries.Metric = withLabel(timeseries.Metric, originLabel, origin)
+				vector = append(vector, timeseries)
+			}
+		case promql.ResultMatrix:
+			for _, timeseries := range result {
+				timeseries.Metric = withLabel(timeseries.Metric, originLabel, origin)
+				matrix = append(matrix, timeseries)
+			}
+		}
+	}
+
+	merged := promql.QueryResponse{Status: "success", Warnings: warnings, Infos: infos}
+	var result any
+	switch {
+	case len(vector) > 0 && len(matrix) > 0:
+		return nil, errors.New("cannot merge results of different types")
+	case len(matrix) > 0:
+		merged.Data.ResultType = "matrix"
+		merged.Data.Result = matrix
+		result = matrix
+	default:
+		merged.Data.ResultType = "vector"
+		merged.Data.Result = vector
+		result = vector
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	merged.Data.ResultRaw = raw
+	return &merged, nil
+}
+
+// withLabel returns the copy of the labels with the given label set.
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	copied := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	copied[name] = value
+	return copied
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+func TestMergeResponses(t *testing.T) {
+	resps := []*promql.QueryResponse{
+		newTestResponse("vector", promql.ResultVector{{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.0, "1"}}}),
+		newTestResponse("vector", promql.ResultVector{{Metric: map[string]string{"job": "a", "cluster": "b"}, Point: []any{1719292597.0, "2"}}}),
+	}
+	origins := []string{"http://a:9090", "http://b:9090"}
+
+	tests := []struct {
+		name        string
+		originLabel string
+		want        promql.ResultVector
+	}{
+		{
+			name:        "default",
+			originLabel: defaultOriginLabel,
+			want: promql.ResultVector{
+				{Metric: map[string]string{"job": "a", "cluster": "http://a:9090"}, Point: []any{1719292597.0, "1"}},
+				{Metric: map[string]string{"job": "a", "cluster": "http://b:9090"}, Point: []any{1719292597.0, "2"}},
+			},
+		},
+		{
+			name:        "custom label",
+			originLabel: "server",
+			want: promql.ResultVector{
+				{Metric: map[string]string{"job": "a", "server": "http://a:9090"}, Point: []any{1719292597.0, "1"}},
+				{Metric: map[string]string{"job": "a", "cluster": "b", "server": "http://b:9090"}, Point: []any{1719292597.0, "2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeResponses(resps, origins, tt.originLabel)
+			if err != nil {
+				t.Fatalf("mergeResponses() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Data.Result, tt.want) {
+				t.Errorf("mergeResponses() result = %#v, want %#v", got.Data.Result, tt.want)
+			}
+		})
+	}
+}
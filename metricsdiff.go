@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runMetricsDiffCommand runs `\metrics-diff <other-url>`, which shows the metric names existing only in either server.
+func (c *CLI) runMetricsDiffCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \metrics-diff <other-url>`)
+	}
+	other, err := c.otherClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	stop := c.PrintProgressingMark()
+	names, err := c.client.MetricNamesContext(ctx)
+	if err != nil {
+		stop()
+		return fmt.Errorf("%s: %w", c.client.Origin(), err)
+	}
+	otherNames, err := other.MetricNamesContext(ctx)
+	stop()
+	if err != nil {
+		return fmt.Errorf("%s: %w", other.Origin(), err)
+	}
+
+	onlyCurrent, onlyOther := diffNames(names, otherNames)
+	table := promql.Table{Header: []string{"metric name", "only in"}}
+	for _, name := range onlyCurrent {
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{name, c.client.Origin()}})
+	}
+	for _, name := range onlyOther {
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{name, other.Origin()}})
+	}
+	c.printTable(&table, fmt.Sprintf("%d metrics only in %s, %d only in %s",
+		len(onlyCurrent), c.client.Origin(), len(onlyOther), other.Origin()))
+	return nil
+}
+
+// diffNames returns the sorted names existing only in a, and the ones only in b.
+func diffNames(a, b []string) ([]string, []string) {
+	inA := make(map[string]bool, len(a))
+	for _, name := range a {
+		inA[name] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, name := range b {
+		inB[name] = true
+	}
+
+	var onlyA, onlyB []string
+	for name := range inA {
+		if !inB[name] {
+			onlyA = append(onlyA, name)
+		}
+	}
+	for name := range inB {
+		if !inA[name] {
+			onlyB = append(onlyB, name)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyA, onlyB
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// runOpenCommand runs `\open`, which opens the last query in the graph page of the Prometheus web UI.
+func (c *CLI) runOpenCommand() error {
+	if c.lastQuery == "" {
+		return errors.New("no previous query")
+	}
+	graphURL, ok := c.client.GraphURL(c.lastQuery)
+	if !ok {
+		fmt.Fprintf(c.out, "%s doesn't provide the Prometheus web UI\n\n", c.client.Origin())
+		return nil
+	}
+	fmt.Fprintf(c.out, "%s\n\n", graphURL)
+	return openBrowser(graphURL)
+}
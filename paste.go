@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	pastePrompt = "....> "
+
+	// The terminal sends the pasted text between the start and the end markers in the bracketed paste mode.
+	bracketedPasteOn    = "\033[?2004h"
+	bracketedPasteOff   = "\033[?2004l"
+	bracketedPasteStart = "\033[200~"
+	bracketedPasteEnd   = "\033[201~"
+)
+
+// readPaste reads the lines as one query until a blank line or a line ending with `;`, for `\paste`.
+func (c *CLI) readPaste(rl *readline.Instance) (string, error) {
+	fmt.Fprintln(c.out, "Paste the query, and end it with a blank line or `;`")
+	rl.SetPrompt(pastePrompt)
+	defer rl.SetPrompt(c.prompt())
+	rl.HistoryDisable()
+	defer rl.HistoryEnable()
+	// Closing brackets must not be inserted in the middle of the multi-line query.
+	c.pasting = true
+	defer func() { c.pasting = false }()
+
+	var lines []string
+	for {
+		line, err := rl.Readline()
+		if err != nil {
+			return "", err
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			break
+		}
+		lines = append(lines, line)
+		if strings.HasSuffix(trimmed, ";") {
+			break
+		}
+	}
+
+	query := strings.Join(lines, "\n")
+	if statements := splitStatements(query); len(statements) > 0 {
+		// The history is saved line by line, so the query is saved in one line without comments.
+		rl.SaveHistory(strings.Join(statements, "; "))
+	}
+	return strings.TrimSpace(query), nil
+}
+
+// bracketedPasteReader strips the bracketed paste markers, and joins the pasted lines into one query.
+// A blank line at the top level ends the query like `;`.
+type bracketedPasteReader struct {
+	r io.ReadCloser
+
+	pasting bool
+	// quote is the quote of the string literal being pasted, or 0 outside of string literals.
+	quote   byte
+	escaped bool
+	comment bool
+	// depth is the nesting level of the brackets, braces and parentheses.
+	depth int
+	// blank is true until the current line has anything but spaces.
+	blank bool
+	// cr is true right after \r, so that \r\n is one newline.
+	cr bool
+
+	// pending is the bytes which may be the beginning of a marker.
+	pending []byte
+	out     []byte
+}
+
+func newBracketedPasteReader(r io.ReadCloser) *bracketedPasteReader {
+	return &bracketedPasteReader{r: r}
+}
+
+func (p *bracketedPasteReader) Read(b []byte) (int, error) {
+	for len(p.out) == 0 {
+		chunk := make([]byte, len(b))
+		n, err := p.r.Read(chunk)
+		for _, ch := range chunk[:n] {
+			p.filter(ch)
+		}
+		if err != nil {
+			p.emitPending()
+			if len(p.out) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	n := copy(b, p.out)
+	p.out = p.out[n:]
+	return n, nil
+}
+
+func (p *bracketedPasteReader) Close() error {
+	return p.r.Close()
+}
+
+func (p *bracketedPasteReader) filter(ch byte) {
+	p.pending = append(p.pending, ch)
+	switch pending := string(p.pending); {
+	case pending == bracketedPasteStart:
+		p.pending = nil
+		p.pasting, p.quote, p.escaped, p.comment, p.depth, p.blank, p.cr = true, 0, false, false, 0, true, false
+	case pending == bracketedPasteEnd:
+		p.pending = nil
+		p.pasting = false
+	case strings.HasPrefix(bracketedPasteStart, pending) || strings.HasPrefix(bracketedPasteEnd, pending):
+		// Wait for the following bytes to tell if it's a marker.
+	default:
+		// Not a marker, though the last ESC may begin one.
+		var last []byte
+		if ch == '\033' {
+			p.pending, last = p.pending[:len(p.pending)-1], []byte{ch}
+		}
+		p.emitPending()
+		p.pending = last
+	}
+}
+
+func (p *bracketedPasteReader) emitPending() {
+	for _, ch := range p.pending {
+		p.emit(ch)
+	}
+	p.pending = nil
+}
+
+func (p *bracketedPasteReader) emit(ch byte) {
+	if !p.pasting {
+		p.out = append(p.out, ch)
+		return
+	}
+
+	if ch == '\n' && p.cr {
+		p.cr = false
+		return
+	}
+	p.cr = ch == '\r'
+	isNewline := ch == '\n' || ch == '\r'
+	switch {
+	case p.comment:
+		if isNewline {
+			p.comment = false
+			p.out = append(p.out, p.newline())
+		}
+		return
+	case p.quote != 0:
+		switch {
+		case p.escaped:
+			p.escaped = false
+		case ch == '\\' && p.quote != '`':
+			p.escaped = true
+		case ch == p.quote:
+			p.quote = 0
+		}
+		// A newline in a string literal is invalid, and left to the server as a space.
+	case ch == '"' || ch == '\'' || ch == '`':
+		p.quote = ch
+	case ch == '#':
+		p.comment, p.blank = true, false
+		return
+	case ch == '(' || ch == '[' || ch == '{':
+		p.depth++
+	case ch == ')' || ch == ']' || ch == '}':
+		p.depth--
+	case isNewline:
+		p.out = append(p.out, p.newline())
+		return
+	}
+	if isNewline || ch == '\t' {
+		ch = ' '
+	}
+	if ch != ' ' {
+		p.blank = false
+	}
+	p.out = append(p.out, ch)
+}
+
+// newline returns the byte replacing the newline, which is `;` for a blank line.
+func (p *bracketedPasteReader) newline() byte {
+	blank := p.blank
+	p.blank = true
+	if blank && p.depth <= 0 {
+		return ';'
+	}
+	return ' '
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBracketedPasteReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "not pasted",
+			input: "up\n",
+			want:  "up\n",
+		},
+		{
+			name:  "operator on its own line",
+			input: bracketedPasteStart + "sum(rate(a[5m]))\n/\nsum(rate(b[5m]))" + bracketedPasteEnd,
+			want:  "sum(rate(a[5m])) / sum(rate(b[5m]))",
+		},
+		{
+			name:  "grouping on the next line",
+			input: bracketedPasteStart + "sum(rate(x[5m]))\n  by (job)\n" + bracketedPasteEnd,
+			want:  "sum(rate(x[5m]))   by (job) ",
+		},
+		{
+			name:  "vector matching on the next line",
+			input: bracketedPasteStart + "rate(a[5m])\n  / on(job)\nrate(b[5m])" + bracketedPasteEnd,
+			want:  "rate(a[5m])   / on(job) rate(b[5m])",
+		},
+		{
+			name:  "prettified by format_query",
+			input: bracketedPasteStart + "sum by (job) (\n\trate(foo[5m])\n)" + bracketedPasteEnd,
+			want:  "sum by (job) (  rate(foo[5m]) )",
+		},
+		{
+			name:  "blank line ends the query",
+			input: bracketedPasteStart + "sum(a)\n/\nsum(b)\n\nup\n" + bracketedPasteEnd,
+			want:  "sum(a) / sum(b) ;up ",
+		},
+		{
+			name:  "CRLF",
+			input: bracketedPasteStart + "sum(a)\r\n/ sum(b)\r\n\r\nup" + bracketedPasteEnd,
+			want:  "sum(a) / sum(b) ;up",
+		},
+		{
+			name:  "blank line in brackets",
+			input: bracketedPasteStart + "sum(\n\n  a\n)" + bracketedPasteEnd,
+			want:  "sum(    a )",
+		},
+		{
+			name:  "comments",
+			input: bracketedPasteStart + "# header\nsum(a) # trailing (\n# middle\n/ sum(b)" + bracketedPasteEnd,
+			want:  " sum(a)   / sum(b)",
+		},
+		{
+			name:  "brackets and newlines in strings",
+			input: bracketedPasteStart + "foo{job=\"a(\"}\nor bar{job='#'}" + bracketedPasteEnd,
+			want:  "foo{job=\"a(\"} or bar{job='#'}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(newBracketedPasteReader(io.NopCloser(strings.NewReader(tt.input))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package promql
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheCapacity = 100
+
+// queryCache is an LRU cache of query responses whose entries expire after the TTL.
+type queryCache struct {
+	ttl      time.Duration
+	capacity int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key       string
+	resp      *QueryResponse
+	expiresAt time.Time
+}
+
+func newQueryCache(ttl time.Duration, capacity int) *queryCache {
+	return &queryCache{
+		ttl:      ttl,
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *queryCache) Get(key string) (*QueryResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.resp, true
+}
+
+func (c *queryCache) Add(key string, resp *QueryResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		entry := elem.Value.(*cacheEntry)
+		entry.resp = resp
+		entry.expiresAt = expiresAt
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, resp: resp, expiresAt: expiresAt})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *queryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *queryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+// cacheKey returns the key identifying the query and the parameters affecting its result.
+func cacheKey(q string, opts QueryOptions) string {
+	return strings.Join([]string{strconv.FormatInt(opts.Time.UnixMilli(), 10), opts.LookbackDelta, strconv.FormatBool(opts.Analyze), q}, "\x00")
+}
@@ -0,0 +1,872 @@
+package promql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// defaultRetryBackoff is used as the wait time before retrying a rate limited request without Retry-After header.
+const defaultRetryBackoff = 1 * time.Second
+
+// QueryResponse is the response of the Query API or the Range Query API.
+type QueryResponse struct {
+	Status   string   `json:"status"`
+	Data     Data     `json:"data"`
+	Error    string   `json:"error"`
+	Warnings []string `json:"warnings"`
+	// Infos is returned by newer Prometheus versions, and absent on older ones.
+	Infos []string `json:"infos"`
+
+	// Cached is true if the response is served from the query cache.
+	Cached bool `json:"-"`
+	// Body is the original response body. It is nil for the response merged from multiple servers.
+	Body []byte `json:"-"`
+	// RequestID is the X-Request-Id of the request, which can be looked up in the server logs.
+	// It is empty for the responses served from the cache or merged from multiple servers.
+	RequestID string `json:"-"`
+}
+
+// JSON response is decoded two times to create Date struct.
+// 1st decode is for populating the ResultRaw field.
+// 2nd decode is for populating the Result field depending on the result type.
+// Format: https://prometheus.io/docs/prometheus/latest/querying/api/#expression-query-result-formats
+type Data struct {
+	ResultType string          `json:"resultType"`
+	ResultRaw  json.RawMessage `json:"result"`
+	// Result could contain either ResultScalar, ResultString, ResultVector, or ResultMatrix.
+	Result any `json:"-"`
+	// Analysis is the breakdown of the query execution requested by QueryOptions.Analyze, or nil if the server
+	// doesn't support it.
+	Analysis *QueryAnalysis `json:"analysis,omitempty"`
+}
+
+// QueryAnalysis is the execution statistics of an operator of the query, such as an aggregation or a selector,
+// returned by Thanos with the Thanos engine. The children are the operators feeding this one.
+type QueryAnalysis struct {
+	Name string `json:"name"`
+	// ExecutionTime is the duration formatted by the server, such as 1.234ms.
+	ExecutionTime string          `json:"executionTime"`
+	PeakSamples   int64           `json:"peakSamples"`
+	TotalSamples  int64           `json:"totalSamples"`
+	Children      []QueryAnalysis `json:"children"`
+}
+
+type ResultScalar []any
+type ResultString []any
+type ResultVector []VectorTimeSeries
+type ResultMatrix []MatrixTimeSeries
+
+type VectorTimeSeries struct {
+	Metric map[string]string `json:"metric"`
+	Point  []any             `json:"value"`
+}
+
+type MatrixTimeSeries struct {
+	Metric map[string]string `json:"metric"`
+	Points [][]any           `json:"values"`
+}
+
+// Client is the client of the Prometheus HTTP API for a server.
+type Client struct {
+	baseURL   string
+	projectID string
+	header    http.Header
+	client    *http.Client
+
+	cache        *queryCache
+	cacheEnabled bool
+
+	// tokenSource is the token source for Google Cloud Monitoring, which is nil for other servers.
+	tokenSource *refreshableTokenSource
+	gcp         GCPOptions
+
+	// queryParams are the additional parameters sent with every query, such as dedup of Thanos.
+	queryParams url.Values
+
+	// recordDir is the directory to record the response bodies of queries to, or empty not to record.
+	recordDir string
+}
+
+// QueryOptions holds the optional parameters for a query.
+type QueryOptions struct {
+	// Time is the evaluation time of the query. The current server time is used if zero.
+	Time time.Time
+	// LookbackDelta overrides the lookback delta of the server if not empty.
+	// It's ignored by servers not supporting the lookback_delta parameter.
+	LookbackDelta string
+	// Timeout bounds the evaluation of the query on the server if not empty, such as "10s". This is independent of
+	// the timeout of the HTTP request, which also covers the transfer of the response.
+	Timeout string
+	// Trace is attached to the underlying HTTP request if not nil.
+	Trace *httptrace.ClientTrace
+	// Analyze requests the breakdown of the query execution in Data.Analysis.
+	// It's ignored by servers not supporting the analyze parameter.
+	Analyze bool
+}
+
+// NewClient returns the client for the server at the base URL, or for Google Cloud Monitoring if the project ID is given.
+// The headers are comma separated "name: value" pairs.
+func NewClient(ctx context.Context, baseURL string, projectID string, headers string, transportOpts TransportOptions, gcpOpts GCPOptions) (*Client, error) {
+	httpClient := &http.Client{Transport: transportOpts.newTransport()}
+	var tokenSource *refreshableTokenSource
+
+	// For Google Cloud Monitoring
+	if projectID != "" {
+		baseURL = fmt.Sprintf("https://monitoring.googleapis.com/v1/projects/%s/location/global/prometheus", projectID)
+		var err error
+		tokenSource, err = newGCPTokenSource(ctx, gcpOpts, httpClient.Transport)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = &http.Client{
+			Transport: &oauth2.Transport{Source: tokenSource, Base: httpClient.Transport},
+		}
+	}
+
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %v", err)
+	}
+
+	var header http.Header
+	if headers != "" {
+		var err error
+		header, err = parseHeaderString(headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if projectID != "" && gcpOpts.QuotaProject != "" {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("X-Goog-User-Project", gcpOpts.QuotaProject)
+	}
+
+	return &Client{
+		baseURL:   baseURL,
+		projectID: projectID,
+		header:    header,
+		client:    httpClient,
+
+		tokenSource: tokenSource,
+		gcp:         gcpOpts,
+	}, nil
+}
+
+// Origin returns the host of the server, which identifies where a result comes from.
+func (c *Client) Origin() string {
+	u, _ := url.Parse(c.baseURL) // ignore error since baseURL is already validated
+	return u.Host
+}
+
+// EnableCache enables caching of query responses for the given TTL.
+func (c *Client) EnableCache(ttl time.Duration) {
+	c.cache = newQueryCache(ttl, defaultCacheCapacity)
+	c.cacheEnabled = true
+}
+
+// SetCacheEnabled toggles the query cache enabled by EnableCache.
+func (c *Client) SetCacheEnabled(enabled bool) error {
+	if c.cache == nil {
+		return errors.New("query cache is not configured")
+	}
+	c.cacheEnabled = enabled
+	return nil
+}
+
+// CacheStats is the state of the query cache.
+type CacheStats struct {
+	Enabled bool
+	TTL     time.Duration
+	Entries int
+}
+
+// CacheStats returns the state of the query cache, or false if the cache is not configured by EnableCache.
+func (c *Client) CacheStats() (CacheStats, bool) {
+	if c.cache == nil {
+		return CacheStats{}, false
+	}
+	return CacheStats{Enabled: c.cacheEnabled, TTL: c.cache.ttl, Entries: c.cache.Len()}, true
+}
+
+// ClearCache removes all the entries from the query cache.
+func (c *Client) ClearCache() {
+	if c.cache != nil {
+		c.cache.Clear()
+	}
+}
+
+// Query runs the instant query evaluated at the current time.
+func (c *Client) Query(q string) (*QueryResponse, error) {
+	return c.QueryContext(context.Background(), q, QueryOptions{})
+}
+
+// QueryWithOptions is the same as Query, but accepts the optional parameters.
+func (c *Client) QueryWithOptions(q string, opts QueryOptions) (*QueryResponse, error) {
+	return c.QueryContext(context.Background(), q, opts)
+}
+
+// QueryContext runs the instant query. Queries with a fixed evaluation time are served from the cache if enabled.
+func (c *Client) QueryContext(ctx context.Context, q string, opts QueryOptions) (*QueryResponse, error) {
+	useCache := c.cacheEnabled && !opts.Time.IsZero()
+	key := cacheKey(q, opts)
+	if useCache {
+		if cached, ok := c.cache.Get(key); ok {
+			resp := *cached
+			resp.Cached = true
+			resp.RequestID = ""
+			return &resp, nil
+		}
+	}
+
+	qr, err := c.query(ctx, q, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		c.cache.Add(key, qr)
+	}
+	return qr, nil
+}
+
+func (c *Client) query(ctx context.Context, q string, opts QueryOptions) (*QueryResponse, error) {
+	req, err := c.NewQueryRequest(q, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Trace != nil {
+		ctx = httptrace.WithClientTrace(ctx, opts.Trace)
+	}
+	req = req.WithContext(ctx)
+	return c.SendQueryRequest(req)
+}
+
+// QueryRange runs the query over the range of time via the Range Query API.
+func (c *Client) QueryRange(q string, start, end time.Time, step time.Duration) (*QueryResponse, error) {
+	return c.QueryRangeContext(context.Background(), q, start, end, step)
+}
+
+// QueryRangeContext is the same as QueryRange, but the request is aborted when the context is done.
+func (c *Client) QueryRangeContext(ctx context.Context, q string, start, end time.Time, step time.Duration) (*QueryResponse, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", q)
+	queryParams.Add("start", formatUnixTime(start))
+	queryParams.Add("end", formatUnixTime(end))
+	queryParams.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	c.addQueryParams(queryParams)
+
+	req, err := c.newRequest(ctx, "/api/v1/query_range", queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendQueryRequest(req)
+}
+
+// apiResponse is the common envelope of the responses from the Prometheus HTTP API.
+type apiResponse struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+// getAPI sends a GET request to the API path, and decodes the data field of the response into v.
+func (c *Client) getAPI(ctx context.Context, path string, queryParams url.Values, v any) error {
+	req, err := c.newRequest(ctx, path, queryParams)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	id := req.Header.Get(requestIDHeader)
+	if err := checkContentType(resp); err != nil {
+		return withRequestID(err, id)
+	}
+
+	r, err := responseBody(resp)
+	if err != nil {
+		return withRequestID(err, id)
+	}
+	var ar apiResponse
+	if err := json.NewDecoder(r).Decode(&ar); err != nil {
+		return withRequestID(err, id)
+	}
+	if ar.Status == "error" {
+		return withRequestID(errors.New(ar.Error), id)
+	}
+	return withRequestID(json.Unmarshal(ar.Data, v), id)
+}
+
+// Series returns the label sets of the time series matching any of the selectors in the time range.
+func (c *Client) Series(matches []string, start, end time.Time) ([]map[string]string, error) {
+	return c.SeriesContext(context.Background(), matches, start, end)
+}
+
+// SeriesContext is the same as Series, but the request is aborted when the context is done.
+func (c *Client) SeriesContext(ctx context.Context, matches []string, start, end time.Time) ([]map[string]string, error) {
+	var series []map[string]string
+	if err := c.getAPI(ctx, "/api/v1/series", matchParams(matches, start, end), &series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// MetricNames returns the names of all the metrics known to the server.
+func (c *Client) MetricNames() ([]string, error) {
+	return c.MetricNamesContext(context.Background())
+}
+
+// MetricNamesContext is the same as MetricNames, but the request is aborted when the context is done.
+func (c *Client) MetricNamesContext(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := c.getAPI(ctx, "/api/v1/label/__name__/values", nil, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// LabelNames returns the label names of the time series matching any of the selectors in the time range.
+func (c *Client) LabelNames(matches []string, start, end time.Time) ([]string, error) {
+	return c.LabelNamesContext(context.Background(), matches, start, end)
+}
+
+// LabelNamesContext is the same as LabelNames, but the request is aborted when the context is done.
+func (c *Client) LabelNamesContext(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	var names []string
+	if err := c.getAPI(ctx, "/api/v1/labels", matchParams(matches, start, end), &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// LabelValues returns the values of the label on the time series matching any of the selectors in the time range.
+func (c *Client) LabelValues(name string, matches []string, start, end time.Time) ([]string, error) {
+	return c.LabelValuesContext(context.Background(), name, matches, start, end)
+}
+
+// LabelValuesContext is the same as LabelValues, but the request is aborted when the context is done.
+func (c *Client) LabelValuesContext(ctx context.Context, name string, matches []string, start, end time.Time) ([]string, error) {
+	var values []string
+	if err := c.getAPI(ctx, "/api/v1/label/"+url.PathEscape(name)+"/values", matchParams(matches, start, end), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// matchParams returns the parameters of the selectors and the time range for the Series API and the Labels API.
+func matchParams(matches []string, start, end time.Time) url.Values {
+	queryParams := url.Values{}
+	for _, match := range matches {
+		queryParams.Add("match[]", match)
+	}
+	queryParams.Add("start", formatUnixTime(start))
+	queryParams.Add("end", formatUnixTime(end))
+	return queryParams
+}
+
+// MetricMetadata is the metadata of a metric returned by the Metadata API.
+type MetricMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+// Metadata returns the metadata of the metric, which may have several entries if the targets disagree.
+func (c *Client) Metadata(metric string) ([]MetricMetadata, error) {
+	return c.MetadataContext(context.Background(), metric)
+}
+
+// MetadataContext is the same as Metadata, but the request is aborted when the context is done.
+func (c *Client) MetadataContext(ctx context.Context, metric string) ([]MetricMetadata, error) {
+	var metadata map[string][]MetricMetadata
+	if err := c.getAPI(ctx, "/api/v1/metadata", url.Values{"metric": []string{metric}}, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata[metric], nil
+}
+
+// BuildInfo returns the build information of the server such as the version and the revision.
+func (c *Client) BuildInfo() (map[string]json.RawMessage, error) {
+	return c.BuildInfoContext(context.Background())
+}
+
+// BuildInfoContext is the same as BuildInfo, but the request is aborted when the context is done.
+func (c *Client) BuildInfoContext(ctx context.Context) (map[string]json.RawMessage, error) {
+	var info map[string]json.RawMessage
+	if err := c.getAPI(ctx, "/api/v1/status/buildinfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// RuntimeInfo returns the runtime information of the server such as the start time and the storage retention.
+func (c *Client) RuntimeInfo() (map[string]json.RawMessage, error) {
+	return c.RuntimeInfoContext(context.Background())
+}
+
+// RuntimeInfoContext is the same as RuntimeInfo, but the request is aborted when the context is done.
+func (c *Client) RuntimeInfoContext(ctx context.Context) (map[string]json.RawMessage, error) {
+	var info map[string]json.RawMessage
+	if err := c.getAPI(ctx, "/api/v1/status/runtimeinfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// TSDBStatus is the cardinality statistics of the TSDB returned by the TSDB Stats API.
+type TSDBStatus struct {
+	HeadStats                   TSDBHeadStats `json:"headStats"`
+	SeriesCountByMetricName     []TSDBStat    `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []TSDBStat    `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []TSDBStat    `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []TSDBStat    `json:"seriesCountByLabelValuePair"`
+}
+
+type TSDBHeadStats struct {
+	NumSeries     uint64 `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int64  `json:"chunkCount"`
+	// MinTime and MaxTime are in Unix milliseconds.
+	MinTime int64 `json:"minTime"`
+	MaxTime int64 `json:"maxTime"`
+}
+
+type TSDBStat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// TSDBStatus returns the cardinality statistics of the TSDB head block.
+func (c *Client) TSDBStatus() (*TSDBStatus, error) {
+	return c.TSDBStatusContext(context.Background())
+}
+
+// TSDBStatusContext is the same as TSDBStatus, but the request is aborted when the context is done.
+func (c *Client) TSDBStatusContext(ctx context.Context) (*TSDBStatus, error) {
+	var status TSDBStatus
+	if err := c.getAPI(ctx, "/api/v1/status/tsdb", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ErrFormatQueryUnsupported is returned by FormatQuery if the server doesn't provide the Formatting API.
+var ErrFormatQueryUnsupported = errors.New("format_query is not supported by the server")
+
+// FormatQuery returns the query pretty-formatted by the server.
+func (c *Client) FormatQuery(q string) (string, error) {
+	return c.FormatQueryContext(context.Background(), q)
+}
+
+// FormatQueryContext is the same as FormatQuery, but the request is aborted when the context is done.
+func (c *Client) FormatQueryContext(ctx context.Context, q string) (string, error) {
+	req, err := c.newRequest(ctx, "/api/v1/format_query", url.Values{"query": {q}})
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	// Servers before Prometheus 2.45, and other implementations of the API may not have the endpoint.
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrFormatQueryUnsupported
+	}
+	if err := checkContentType(resp); err != nil {
+		return "", err
+	}
+
+	r, err := responseBody(resp)
+	if err != nil {
+		return "", err
+	}
+	var ar apiResponse
+	if err := json.NewDecoder(r).Decode(&ar); err != nil {
+		return "", err
+	}
+	if ar.Status == "error" {
+		return "", errors.New(ar.Error)
+	}
+	var formatted string
+	if err := json.Unmarshal(ar.Data, &formatted); err != nil {
+		return "", err
+	}
+	return formatted, nil
+}
+
+// ExemplarSeries is the exemplars of a time series returned by the Exemplars API.
+type ExemplarSeries struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Exemplars    []Exemplar        `json:"exemplars"`
+}
+
+// Exemplar is a sample with the labels such as trace_id, which links the metric to a trace.
+type Exemplar struct {
+	Labels    map[string]string `json:"labels"`
+	Value     string            `json:"value"`
+	Timestamp float64           `json:"timestamp"`
+}
+
+// QueryExemplars returns the exemplars of the time series selected by the query in the time range.
+func (c *Client) QueryExemplars(q string, start, end time.Time) ([]ExemplarSeries, error) {
+	return c.QueryExemplarsContext(context.Background(), q, start, end)
+}
+
+// QueryExemplarsContext is the same as QueryExemplars, but the request is aborted when the context is done.
+func (c *Client) QueryExemplarsContext(ctx context.Context, q string, start, end time.Time) ([]ExemplarSeries, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", q)
+	queryParams.Add("start", formatUnixTime(start))
+	queryParams.Add("end", formatUnixTime(end))
+
+	var series []ExemplarSeries
+	if err := c.getAPI(ctx, "/api/v1/query_exemplars", queryParams, &series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// SendQueryRequest sends the request built such as by NewQueryRequest, and decodes the response without the cache.
+func (c *Client) SendQueryRequest(req *http.Request) (*QueryResponse, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	id := req.Header.Get(requestIDHeader)
+	if err := checkContentType(resp); err != nil {
+		return nil, withRequestID(err, id)
+	}
+
+	r, err := responseBody(resp)
+	if err != nil {
+		return nil, withRequestID(err, id)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, withRequestID(err, id)
+	}
+	c.record(req, body)
+	qr, err := DecodeQueryResponse(body)
+	if err != nil {
+		return nil, withRequestID(err, id)
+	}
+	qr.RequestID = id
+	return qr, nil
+}
+
+// DecodeQueryResponse decodes the response body of the Query API or the Range Query API,
+// including the result according to the result type.
+func DecodeQueryResponse(body []byte) (*QueryResponse, error) {
+	var qr QueryResponse
+	if err := json.Unmarshal(body, &qr); err != nil {
+		return nil, err
+	}
+	qr.Body = body
+
+	if qr.Status == "error" {
+		return nil, errors.New(qr.Error)
+	}
+
+	switch qr.Data.ResultType {
+	case "scalar":
+		var result ResultScalar
+		if err := json.Unmarshal(qr.Data.ResultRaw, &result); err != nil {
+			return nil, err
+		}
+		if err := checkPoint(result); err != nil {
+			return nil, err
+		}
+		qr.Data.Result = result
+	case "string":
+		var result ResultString
+		if err := json.Unmarshal(qr.Data.ResultRaw, &result); err != nil {
+			return nil, err
+		}
+		if err := checkPoint(result); err != nil {
+			return nil, err
+		}
+		qr.Data.Result = result
+	case "vector":
+		var result ResultVector
+		if err := json.Unmarshal(qr.Data.ResultRaw, &result); err != nil {
+			return nil, err
+		}
+		for _, timeseries := range result {
+			if err := checkPoint(timeseries.Point); err != nil {
+				return nil, err
+			}
+		}
+		qr.Data.Result = result
+	case "matrix":
+		var result ResultMatrix
+		if err := json.Unmarshal(qr.Data.ResultRaw, &result); err != nil {
+			return nil, err
+		}
+		for _, timeseries := range result {
+			for _, point := range timeseries.Points {
+				if err := checkPoint(point); err != nil {
+					return nil, err
+				}
+			}
+		}
+		qr.Data.Result = result
+	default:
+		return nil, fmt.Errorf("unsupported result type: %q", qr.Data.ResultType)
+	}
+
+	return &qr, nil
+}
+
+// checkPoint returns an error unless the point is a pair of the timestamp in float seconds and the value string,
+// so that the users of the result can assert the types.
+func checkPoint(point []any) error {
+	if len(point) == 2 {
+		_, okTimestamp := point[0].(float64)
+		_, okValue := point[1].(string)
+		if okTimestamp && okValue {
+			return nil
+		}
+	}
+	b, _ := json.Marshal(point)
+	return fmt.Errorf("invalid sample: %s, expected [<timestamp>, \"<value>\"]", b)
+}
+
+// do sends the request with a new X-Request-Id, and retries it once on 429 Too Many Requests.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if id := newRequestID(); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	} else {
+		req.Header.Del(requestIDHeader)
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The access token can be revoked or expire during a long session, so refresh it once and retry.
+	if resp.StatusCode == http.StatusUnauthorized && c.tokenSource != nil {
+		resp.Body.Close()
+		slog.Info("refreshing the access token", "url", req.URL.String(), "request_id", req.Header.Get(requestIDHeader), "status", resp.StatusCode)
+		if err := c.Reauth(); err != nil {
+			return nil, err
+		}
+		resp, err = c.send(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unauthorized even after refreshing the access token (status: %s)", resp.Status)
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		wait = defaultRetryBackoff
+	}
+	slog.Info("retrying the rate limited request", "url", req.URL.String(), "request_id", req.Header.Get(requestIDHeader), "wait", wait)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	return c.send(req)
+}
+
+// send sends the request once, logging the start and the end of it.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(requestIDHeader)
+	slog.Debug("request started", "method", req.Method, "url", req.URL.String(), "request_id", id)
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("request failed", "url", req.URL.String(), "request_id", id, "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+	slog.Debug("request finished", "url", req.URL.String(), "request_id", id, "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
+
+// checkContentType returns an error if the response is not JSON, such as an HTML error page of a proxy.
+func checkContentType(resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+	return fmt.Errorf("unexpected content type %q (status: %s)", contentType, resp.Status)
+}
+
+// parseRetryAfter parses the value of Retry-After header, which is either delay seconds or HTTP-date.
+func parseRetryAfter(val string, now time.Time) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if wait := t.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// NewQueryRequest builds the HTTP request for the Query API without sending it.
+func (c *Client) NewQueryRequest(q string, opts QueryOptions) (*http.Request, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", q)
+	if !opts.Time.IsZero() {
+		queryParams.Add("time", formatUnixTime(opts.Time))
+	}
+	if opts.LookbackDelta != "" {
+		queryParams.Add("lookback_delta", opts.LookbackDelta)
+	}
+	if opts.Timeout != "" {
+		queryParams.Add("timeout", opts.Timeout)
+	}
+	if opts.Analyze {
+		queryParams.Add("analyze", "true")
+	}
+	c.addQueryParams(queryParams)
+	return c.newRequest(context.Background(), "/api/v1/query", queryParams)
+}
+
+// SetHTTPClient replaces the HTTP client sending the requests, such as in tests.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	if c.tokenSource != nil {
+		wrapped := *httpClient
+		wrapped.Transport = &oauth2.Transport{Source: c.tokenSource, Base: httpClient.Transport}
+		httpClient = &wrapped
+	}
+	c.client = httpClient
+}
+
+// SetQueryParam sets the parameter sent with every query request.
+func (c *Client) SetQueryParam(name, value string) {
+	if c.queryParams == nil {
+		c.queryParams = url.Values{}
+	}
+	c.queryParams.Set(name, value)
+}
+
+func (c *Client) addQueryParams(queryParams url.Values) {
+	for name, values := range c.queryParams {
+		queryParams[name] = values
+	}
+}
+
+// newRequest builds the GET request for the API path relative to the base URL, which is canceled with the context.
+func (c *Client) newRequest(ctx context.Context, path string, queryParams url.Values) (*http.Request, error) {
+	u, _ := url.Parse(c.baseURL) // ignore error since baseURL is already validated
+	u = u.JoinPath(path)
+	u.RawQuery = queryParams.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	return req, nil
+}
+
+// CurlCommand returns the curl command equivalent to the given request.
+// Values of sensitive headers are redacted unless showSecrets is true.
+func (c *Client) CurlCommand(req *http.Request, showSecrets bool) string {
+	args := []string{"curl"}
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, val := range req.Header[name] {
+			if !showSecrets && IsSensitiveHeader(name) {
+				val = "REDACTED"
+			}
+			args = append(args, "-H", shellQuote(fmt.Sprintf("%s: %s", name, val)))
+		}
+	}
+
+	// For Google Cloud Monitoring, the access token is attached by the OAuth2 transport.
+	if c.projectID != "" {
+		printToken := "gcloud auth print-access-token"
+		if c.gcp.ImpersonateServiceAccount != "" {
+			printToken += " --impersonate-service-account=" + c.gcp.ImpersonateServiceAccount
+		}
+		args = append(args, "-H", fmt.Sprintf(`"Authorization: Bearer $(%s)"`, printToken))
+	}
+
+	args = append(args, shellQuote(req.URL.String()))
+	return strings.Join(args, " ")
+}
+
+// formatUnixTime formats the time as Unix timestamp in seconds with millisecond precision.
+func formatUnixTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// IsSensitiveHeader returns true if the value of the header should be redacted, such as Authorization.
+func IsSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	return strings.Contains(name, "token") || strings.Contains(name, "secret") || strings.Contains(name, "key")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func parseHeaderString(headers string) (http.Header, error) {
+	header := make(http.Header, 0)
+	for _, h := range strings.Split(headers, ",") {
+		key, val, found := strings.Cut(h, ":")
+		if !found {
+			return header, fmt.Errorf("invalid header: %q", h)
+		}
+		header.Add(strings.TrimSpace(key), strings.TrimSpace(val))
+	}
+	return header, nil
+}
@@ -0,0 +1,277 @@
+package promql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// cannedResponse is a response of the fake server.
+type cannedResponse struct {
+	status     int
+	retryAfter string
+	body       string
+}
+
+func TestQueryContext(t *testing.T) {
+	const vector = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"prometheus"},"value":[1719292597.171,"1"]}]}}`
+
+	tests := []struct {
+		name      string
+		responses []cannedResponse
+		want      ResultVector
+		wantErr   string
+		wantCalls int32
+	}{
+		{
+			name:      "success",
+			responses: []cannedResponse{{status: http.StatusOK, body: vector}},
+			want: ResultVector{{
+				Metric: map[string]string{"__name__": "up", "job": "prometheus"},
+				Point:  []any{1719292597.171, "1"},
+			}},
+			wantCalls: 1,
+		},
+		{
+			name: "API error",
+			responses: []cannedResponse{{
+				status: http.StatusBadRequest,
+				body:   `{"status":"error","errorType":"bad_data","error":"invalid parameter \"query\": parse error"}`,
+			}},
+			wantErr:   `invalid parameter "query": parse error`,
+			wantCalls: 1,
+		},
+		{
+			name: "retry after 429",
+			responses: []cannedResponse{
+				{status: http.StatusTooManyRequests, retryAfter: "0", body: `{"status":"error","error":"too many requests"}`},
+				{status: http.StatusOK, body: vector},
+			},
+			want: ResultVector{{
+				Metric: map[string]string{"__name__": "up", "job": "prometheus"},
+				Point:  []any{1719292597.171, "1"},
+			}},
+			wantCalls: 2,
+		},
+		{
+			name: "429 twice",
+			responses: []cannedResponse{
+				{status: http.StatusTooManyRequests, retryAfter: "0", body: `{"status":"error","error":"too many requests"}`},
+				{status: http.StatusTooManyRequests, retryAfter: "0", body: `{"status":"error","error":"too many requests"}`},
+			},
+			wantErr:   "too many requests",
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var requestIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1)) - 1
+				requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+				if n >= len(tt.responses) {
+					t.Errorf("unexpected request #%d", n+1)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if got := r.FormValue("query"); got != "up" {
+					t.Errorf("query = %q, want %q", got, "up")
+				}
+				res := tt.responses[n]
+				w.Header().Set("Content-Type", "application/json")
+				if res.retryAfter != "" {
+					w.Header().Set("Retry-After", res.retryAfter)
+				}
+				w.WriteHeader(res.status)
+				w.Write([]byte(res.body))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(context.Background(), server.URL, "", "", DefaultTransportOptions(), GCPOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.SetHTTPClient(server.Client())
+
+			resp, err := client.QueryContext(context.Background(), "up", QueryOptions{})
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("requests = %d, want %d", got, tt.wantCalls)
+			}
+			// The retries are sent with the same request ID.
+			id := requestIDs[0]
+			for _, retryID := range requestIDs[1:] {
+				if id == "" || retryID != id {
+					t.Errorf("request IDs = %q, want the same non-empty ID", requestIDs)
+				}
+			}
+			if tt.wantErr != "" {
+				if want := fmt.Sprintf("%s (request ID: %s)", tt.wantErr, id); err == nil || err.Error() != want {
+					t.Fatalf("QueryContext() error = %v, want %q", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryContext() error = %v", err)
+			}
+			if got := resp.Data.Result; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryContext() result = %#v, want %#v", got, tt.want)
+			}
+			if resp.RequestID != id {
+				t.Errorf("QueryContext() request ID = %q, want %q", resp.RequestID, id)
+			}
+		})
+	}
+}
+
+func TestQueryContext_FreshRequestID(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1719292597.171,"1"]}}`))
+	}))
+	defer server.Close()
+
+	// The ID given by the headers must not be sent with every request.
+	client, err := NewClient(context.Background(), server.URL, "", "X-Request-Id: fixed", DefaultTransportOptions(), GCPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetHTTPClient(server.Client())
+	for i := 0; i < 2; i++ {
+		if _, err := client.QueryContext(context.Background(), "1", QueryOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requestIDs) != 2 || requestIDs[0] == "fixed" || requestIDs[1] == "fixed" || requestIDs[0] == requestIDs[1] {
+		t.Errorf("request IDs = %q, want two different generated IDs", requestIDs)
+	}
+}
+
+// newTestClient returns the client sending the requests to the server responding by the handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(context.Background(), server.URL, "", "", DefaultTransportOptions(), GCPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetHTTPClient(server.Client())
+	return client
+}
+
+func TestQueryContext_VictoriaMetrics(t *testing.T) {
+	// VictoriaMetrics adds isPartial and stats, which are not in the Prometheus API.
+	const body = `{"status":"success","isPartial":false,"data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"node"},"value":[1719292597.171,"1"]}]},"stats":{"seriesFetched":"1","executionTimeMsec":2}}`
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/json" {
+			t.Errorf("Accept = %q, want application/json", got)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(body))
+	})
+
+	resp, err := client.QueryContext(context.Background(), "up", QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	want := ResultVector{{Metric: map[string]string{"__name__": "up", "job": "node"}, Point: []any{1719292597.171, "1"}}}
+	if !reflect.DeepEqual(resp.Data.Result, want) {
+		t.Errorf("QueryContext() result = %#v, want %#v", resp.Data.Result, want)
+	}
+}
+
+func TestQueryContext_ContentType(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"scalar","result":[1719292597.171,"1"]}}`
+	tests := []struct {
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{contentType: "application/json", body: body},
+		{contentType: "application/json; charset=utf-8", body: body},
+		{contentType: "application/vnd.api+json", body: body},
+		// Some backends omit Content-Type.
+		{contentType: "", body: body},
+		{contentType: "text/html; charset=utf-8", body: "<html>502 Bad Gateway</html>", wantErr: true},
+		{contentType: "text/plain", body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				// Without Content-Type, net/http sniffs it from the body.
+				w.Header()["Content-Type"] = nil
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write([]byte(tt.body))
+			})
+			_, err := client.QueryContext(context.Background(), "1", QueryOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("QueryContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeQueryResponse_UnknownFields(t *testing.T) {
+	// Unknown fields at every level, such as the ones of Thanos and Cortex, are ignored.
+	const body = `{"status":"success","partialResponseStrategy":"WARN","data":{"resultType":"matrix","seriesStats":{"series":1},"result":[{"metric":{"job":"a"},"values":[[1719292597.171,"1"]],"histograms":null}]},"warnings":["partial"],"stats":{"timings":{}}}`
+	resp, err := DecodeQueryResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeQueryResponse() error = %v", err)
+	}
+	want := ResultMatrix{{Metric: map[string]string{"job": "a"}, Points: [][]any{{1719292597.171, "1"}}}}
+	if !reflect.DeepEqual(resp.Data.Result, want) {
+		t.Errorf("DecodeQueryResponse() result = %#v, want %#v", resp.Data.Result, want)
+	}
+	if !reflect.DeepEqual(resp.Warnings, []string{"partial"}) {
+		t.Errorf("DecodeQueryResponse() warnings = %q, want [partial]", resp.Warnings)
+	}
+}
+
+func TestSetQueryParam(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		for name, want := range map[string]string{"dedup": "false", "partial_response": "true", "query": "up"} {
+			if got := r.FormValue(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	})
+	client.SetQueryParam("dedup", "false")
+	client.SetQueryParam("partial_response", "true")
+	if _, err := client.QueryContext(context.Background(), "up", QueryOptions{}); err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+}
+
+func TestDecodeQueryResponse_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "string timestamp", body: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":["1719292597.171","1"]}]}}`},
+		{name: "number value", body: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1719292597.171,1]}]}}`},
+		{name: "short point", body: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1719292597.171]]}]}}`},
+		{name: "no value", body: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"}}]}}`},
+		{name: "empty scalar", body: `{"status":"success","data":{"resultType":"scalar","result":[]}}`},
+		{name: "non-string label", body: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":1},"value":[1719292597.171,"1"]}]}}`},
+		{name: "result not array", body: `{"status":"success","data":{"resultType":"matrix","result":{"a":1}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp, err := DecodeQueryResponse([]byte(tt.body)); err == nil {
+				t.Errorf("DecodeQueryResponse() = %#v, want an error", resp.Data.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Package promql provides the client of the Prometheus HTTP API and the writers of the results in tabular formats,
+// which are used by promql-cli and can be imported by other tools.
+//
+// The client sends instant and range queries, and the other APIs such as series and metadata:
+//
+//	client, err := promql.NewClient(ctx, "http://localhost:9090", "", "", promql.DefaultTransportOptions(), promql.GCPOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := client.Query(`up{job="prometheus"}`)
+//	if err != nil {
+//		return err
+//	}
+//	for _, series := range resp.Data.Result.(promql.ResultVector) {
+//		fmt.Println(series.Metric, series.Point[1])
+//	}
+//
+// Each method sending a request has the variant taking a context, such as QueryContext, to cancel the request
+// or to set the deadline:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+//	defer cancel()
+//	resp, err := client.QueryContext(ctx, `up{job="prometheus"}`, promql.QueryOptions{})
+//
+// The HTTP client can be replaced by SetHTTPClient, e.g. to send the requests to httptest.Server in tests:
+//
+//	server := httptest.NewServer(handler)
+//	client, err := promql.NewClient(ctx, server.URL, "", "", promql.DefaultTransportOptions(), promql.GCPOptions{})
+//	client.SetHTTPClient(server.Client())
+//
+// A Table is written as CSV, TSV, Markdown, JSON or the expanded form:
+//
+//	table := &promql.Table{Header: []string{"job", "value"}, Rows: []promql.Row{{Columns: []string{"prometheus", "1"}}}}
+//	err := promql.WriteCSV(os.Stdout, table, false)
+package promql
@@ -0,0 +1,73 @@
+package promql
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// BaseURL returns the base URL of the server, which is the one of Google Cloud Monitoring if the project is given.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Header returns a copy of the headers sent with every request.
+func (c *Client) Header() http.Header {
+	return c.header.Clone()
+}
+
+// Timeout returns the timeout of the requests, or zero if there's no timeout.
+func (c *Client) Timeout() time.Duration {
+	return c.client.Timeout
+}
+
+// AuthMode returns how requests are authenticated: gcm, bearer, basic, or none.
+func (c *Client) AuthMode() string {
+	if c.projectID != "" {
+		if c.gcp.ImpersonateServiceAccount != "" {
+			return "gcm (impersonating " + c.gcp.ImpersonateServiceAccount + ")"
+		}
+		return "gcm"
+	}
+	scheme, _, _ := strings.Cut(c.header.Get("Authorization"), " ")
+	switch strings.ToLower(scheme) {
+	case "":
+		return "none"
+	case "bearer":
+		return "bearer"
+	case "basic":
+		return "basic"
+	default:
+		return strings.ToLower(scheme)
+	}
+}
+
+// Proxy returns the proxy URL used for requests to the server, or empty if no proxy is used.
+func (c *Client) Proxy() (string, error) {
+	rt := c.client.Transport
+	// For Google Cloud Monitoring, the transport is wrapped with OAuth2 transport.
+	if oauth2Transport, ok := rt.(*oauth2.Transport); ok {
+		rt = oauth2Transport.Base
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return "", nil
+	}
+
+	req, err := c.newRequest(context.Background(), "/", nil)
+	if err != nil {
+		return "", err
+	}
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil {
+		return "", err
+	}
+	proxyURL.User = nil // don't show the credentials
+	return proxyURL.String(), nil
+}
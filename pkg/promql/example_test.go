@@ -0,0 +1,50 @@
+package promql_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+func ExampleClient_QueryContext() {
+	// The server stands in for Prometheus, responding to every query with the same vector.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"prometheus"},"value":[1719292597.171,"1"]}]}}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := promql.NewClient(ctx, server.URL, "", "", promql.DefaultTransportOptions(), promql.GCPOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client.SetHTTPClient(server.Client())
+
+	resp, err := client.QueryContext(ctx, `up{job="prometheus"}`, promql.QueryOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, series := range resp.Data.Result.(promql.ResultVector) {
+		fmt.Println(series.Metric["job"], series.Point[1])
+	}
+	// Output: prometheus 1
+}
+
+func ExampleWriteCSV() {
+	table := &promql.Table{
+		Header: []string{"job", "value"},
+		Rows:   []promql.Row{{Columns: []string{"prometheus", "1"}}},
+	}
+	if err := promql.WriteCSV(os.Stdout, table, false); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// job,value
+	// prometheus,1
+}
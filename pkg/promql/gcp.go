@@ -0,0 +1,140 @@
+package promql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// refreshableTokenSource is an oauth2.TokenSource whose underlying token source can be recreated,
+// which forces to obtain a new token even if the cached one is not expired yet.
+type refreshableTokenSource struct {
+	ctx       context.Context
+	newSource func(context.Context) (oauth2.TokenSource, error)
+
+	mu  sync.Mutex
+	src oauth2.TokenSource
+}
+
+func newRefreshableTokenSource(ctx context.Context, newSource func(context.Context) (oauth2.TokenSource, error)) (*refreshableTokenSource, error) {
+	src, err := newSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &refreshableTokenSource{ctx: ctx, newSource: newSource, src: src}, nil
+}
+
+// GCPOptions are the options for Google Cloud Monitoring.
+type GCPOptions struct {
+	// QuotaProject is the project billed for the API requests instead of the project of the credentials.
+	QuotaProject string
+	// ImpersonateServiceAccount is the email of the service account to impersonate.
+	ImpersonateServiceAccount string
+}
+
+// newGCPTokenSource returns the token source using Application Default Credentials, or the impersonated service account.
+func newGCPTokenSource(ctx context.Context, opts GCPOptions, transport http.RoundTripper) (*refreshableTokenSource, error) {
+	return newRefreshableTokenSource(ctx, func(ctx context.Context) (oauth2.TokenSource, error) {
+		src, err := google.DefaultTokenSource(ctx, gcpScope)
+		if err != nil {
+			return nil, err
+		}
+		if opts.ImpersonateServiceAccount == "" {
+			return src, nil
+		}
+		return oauth2.ReuseTokenSource(nil, &impersonatedTokenSource{
+			ctx:            ctx,
+			client:         &http.Client{Transport: &oauth2.Transport{Source: src, Base: transport}},
+			serviceAccount: opts.ImpersonateServiceAccount,
+		}), nil
+	})
+}
+
+// impersonatedTokenSource obtains the access token of the service account by the IAM Service Account Credentials API.
+// The client must be authorized with the credentials having the Service Account Token Creator role on it.
+type impersonatedTokenSource struct {
+	ctx            context.Context
+	client         *http.Client
+	serviceAccount string
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	body, err := json.Marshal(map[string]any{"scope": []string{gcpScope}})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken",
+		url.PathEscape(s.serviceAccount))
+	req, err := http.NewRequestWithContext(s.ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %w", s.serviceAccount, err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		AccessToken string    `json:"accessToken"`
+		ExpireTime  time.Time `json:"expireTime"`
+		Error       struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s: %s", s.serviceAccount, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to impersonate %s: %s", s.serviceAccount, tokenResp.Error.Message)
+	}
+	return &oauth2.Token{AccessToken: tokenResp.AccessToken, TokenType: "Bearer", Expiry: tokenResp.ExpireTime}, nil
+}
+
+func (s *refreshableTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	src := s.src
+	s.mu.Unlock()
+	return src.Token()
+}
+
+// Refresh recreates the underlying token source and obtains a new token.
+// The current token source is kept if it fails.
+func (s *refreshableTokenSource) Refresh() error {
+	src, err := s.newSource(s.ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := src.Token(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src = src
+	return nil
+}
+
+// Reauth forces to obtain a new access token for Google Cloud Monitoring.
+func (c *Client) Reauth() error {
+	if c.tokenSource == nil {
+		return errors.New("reauthentication is only supported for Google Cloud Monitoring")
+	}
+	if err := c.tokenSource.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh the access token: %w", err)
+	}
+	return nil
+}
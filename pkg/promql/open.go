@@ -0,0 +1,30 @@
+package promql
+
+import (
+	"net/url"
+	"strings"
+)
+
+// managedServiceHostSuffixes are the hosts of the managed services which provide the Prometheus API without the web UI.
+var managedServiceHostSuffixes = []string{
+	".prometheus.monitor.azure.com", // Azure Monitor managed service for Prometheus
+	".amazonaws.com",                // Amazon Managed Service for Prometheus
+}
+
+// GraphURL returns the URL of the graph page showing the query in the table tab of the Prometheus web UI.
+// False is returned for the backends without the web UI, such as Google Cloud Monitoring.
+func (c *Client) GraphURL(q string) (string, bool) {
+	if c.projectID != "" {
+		return "", false
+	}
+	u, _ := url.Parse(c.baseURL) // ignore error since baseURL is already validated
+	for _, suffix := range managedServiceHostSuffixes {
+		if strings.HasSuffix(u.Hostname(), suffix) {
+			return "", false
+		}
+	}
+
+	u = u.JoinPath("/graph")
+	u.RawQuery = url.Values{"g0.expr": {q}, "g0.tab": {"1"}}.Encode()
+	return u.String(), true
+}
@@ -0,0 +1,58 @@
+package promql
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// recordMetadata is written to the sidecar file of each recorded response.
+type recordMetadata struct {
+	Query      string              `json:"query"`
+	URL        string              `json:"url"`
+	Params     map[string][]string `json:"params"`
+	RecordedAt time.Time           `json:"recorded_at"`
+}
+
+// SetRecordDir enables recording the response bodies of queries to the directory, which can be decoded by DecodeQueryResponse.
+func (c *Client) SetRecordDir(dir string) {
+	c.recordDir = dir
+}
+
+// record writes the response body and the .meta.json file to the record directory. Failures are only logged.
+func (c *Client) record(req *http.Request, body []byte) {
+	if c.recordDir == "" {
+		return
+	}
+	now := time.Now()
+	f, err := os.CreateTemp(c.recordDir, now.UTC().Format("20060102T150405.000Z")+"-*.json")
+	if err != nil {
+		slog.Warn("failed to record the response", "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(body); err != nil {
+		slog.Warn("failed to record the response", "file", f.Name(), "error", err)
+		return
+	}
+
+	params := req.URL.Query()
+	meta, err := json.MarshalIndent(recordMetadata{
+		Query:      params.Get("query"),
+		URL:        c.baseURL,
+		Params:     params,
+		RecordedAt: now,
+	}, "", "  ")
+	if err != nil {
+		slog.Warn("failed to record the metadata", "error", err)
+		return
+	}
+	metaPath := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())) + ".meta.json"
+	if err := os.WriteFile(metaPath, append(meta, '\n'), 0o644); err != nil {
+		slog.Warn("failed to record the metadata", "file", metaPath, "error", err)
+	}
+}
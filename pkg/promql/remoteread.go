@@ -0,0 +1,229 @@
+package promql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file implements the client of the Prometheus remote read protocol (SAMPLES response type)
+// with a minimal protobuf encoder and decoder, so that the default JSON path doesn't need extra dependencies.
+// Protocol: https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// remoteReadMatcherTypes maps the label matcher operators to prometheus.LabelMatcher.Type.
+var remoteReadMatcherTypes = map[string]uint64{"=": 0, "!=": 1, "=~": 2, "!~": 3}
+
+// LabelMatcher is a label matcher of the series read by RemoteRead, such as job="prometheus".
+// Op is one of "=", "!=", "=~" and "!~". The metric name is matched by the __name__ label.
+type LabelMatcher struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// RemoteRead reads the samples of the series matching all the matchers in the time range by the remote read protocol.
+// The base URL of the client is the remote read endpoint. The response is in the same shape as a range query.
+func (c *Client) RemoteRead(matchers []LabelMatcher, start, end time.Time) (*QueryResponse, error) {
+	return c.RemoteReadContext(context.Background(), matchers, start, end)
+}
+
+// RemoteReadContext is the same as RemoteRead, but the request is aborted when the context is done.
+func (c *Client) RemoteReadContext(ctx context.Context, matchers []LabelMatcher, start, end time.Time) (*QueryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(snappyEncode(encodeReadRequest(matchers, start, end))))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote read failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	decoded, err := snappyDecode(body)
+	if err != nil {
+		return nil, err
+	}
+	matrix, err := decodeReadResponse(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote read response: %w", err)
+	}
+
+	raw, err := json.Marshal(matrix)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResponse{
+		Status: "success",
+		Data:   Data{ResultType: "matrix", ResultRaw: raw, Result: matrix},
+	}, nil
+}
+
+// encodeReadRequest encodes prometheus.ReadRequest with one query.
+func encodeReadRequest(matchers []LabelMatcher, start, end time.Time) []byte {
+	var query []byte
+	query = appendVarintField(query, 1, uint64(start.UnixMilli()))
+	query = appendVarintField(query, 2, uint64(end.UnixMilli()))
+	for _, m := range matchers {
+		var matcher []byte
+		matcher = appendVarintField(matcher, 1, remoteReadMatcherTypes[m.Op])
+		matcher = appendBytesField(matcher, 2, []byte(m.Name))
+		matcher = appendBytesField(matcher, 3, []byte(m.Value))
+		query = appendBytesField(query, 3, matcher)
+	}
+	return appendBytesField(nil, 1, query)
+}
+
+// decodeReadResponse decodes prometheus.ReadResponse into the matrix.
+func decodeReadResponse(b []byte) (ResultMatrix, error) {
+	matrix := ResultMatrix{}
+	err := walkFields(b, func(num int, typ int, v uint64, data []byte) error {
+		if num != 1 || typ != wireBytes { // results
+			return nil
+		}
+		return walkFields(data, func(num int, typ int, v uint64, data []byte) error {
+			if num != 1 || typ != wireBytes { // timeseries
+				return nil
+			}
+			timeseries, err := decodeTimeSeries(data)
+			if err != nil {
+				return err
+			}
+			matrix = append(matrix, timeseries)
+			return nil
+		})
+	})
+	return matrix, err
+}
+
+func decodeTimeSeries(b []byte) (MatrixTimeSeries, error) {
+	timeseries := MatrixTimeSeries{Metric: map[string]string{}, Points: [][]any{}}
+	err := walkFields(b, func(num int, typ int, v uint64, data []byte) error {
+		if typ != wireBytes {
+			return nil
+		}
+		switch num {
+		case 1: // labels
+			var name, value string
+			err := walkFields(data, func(num int, typ int, v uint64, data []byte) error {
+				switch {
+				case num == 1 && typ == wireBytes:
+					name = string(data)
+				case num == 2 && typ == wireBytes:
+					value = string(data)
+				}
+				return nil
+			})
+			timeseries.Metric[name] = value
+			return err
+		case 2: // samples
+			var value float64
+			var timestamp int64
+			err := walkFields(data, func(num int, typ int, v uint64, data []byte) error {
+				switch {
+				case num == 1 && typ == wireFixed64:
+					value = math.Float64frombits(v)
+				case num == 2 && typ == wireVarint:
+					timestamp = int64(v)
+				}
+				return nil
+			})
+			timeseries.Points = append(timeseries.Points, []any{float64(timestamp) / 1000, strconv.FormatFloat(value, 'f', -1, 64)})
+			return err
+		}
+		return nil
+	})
+	return timeseries, err
+}
+
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, num int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+var errInvalidProtobuf = errors.New("invalid protobuf")
+
+// walkFields calls fn for each field in the protobuf message. v is set for varint and fixed64 fields,
+// and data is set for length-delimited fields. Fixed32 fields are skipped.
+func walkFields(b []byte, fn func(num int, typ int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errInvalidProtobuf
+		}
+		b = b[n:]
+		num, typ := int(key>>3), int(key&0x07)
+
+		var v uint64
+		var data []byte
+		switch typ {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errInvalidProtobuf
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errInvalidProtobuf
+			}
+			v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return errInvalidProtobuf
+			}
+			data = b[n : n+int(length)]
+			b = b[n+int(length):]
+		case 5: // fixed32
+			if len(b) < 4 {
+				return errInvalidProtobuf
+			}
+			b = b[4:]
+			continue
+		default:
+			return errInvalidProtobuf
+		}
+		if err := fn(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package promql
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The fixtures are encoded independently of this package, following prometheus/prompb/remote.proto.
+
+// readResponseFixture is ReadResponse with one series up{job="node"} of the samples 1 at 1719292597.171 and
+// 0.5 at 1719292657.171.
+const readResponseFixture = "0a430a410a0e0a085f5f6e616d655f5f120275700a0b0a036a6f6212046e6f6465121009000000000000f03f10b3a7ccee8432121009000000000000e03f1093fccfee8432"
+
+// readRequestFixture is ReadRequest with one query for up{job=~"no.*"} from 1s to 2s.
+const readRequestFixture = "0a2708e80710d00f1a10080012085f5f6e616d655f5f1a0275701a0d080212036a6f621a046e6f2e2a"
+
+func TestDecodeReadResponse(t *testing.T) {
+	b, err := hex.DecodeString(readResponseFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeReadResponse(b)
+	if err != nil {
+		t.Fatalf("decodeReadResponse() error = %v", err)
+	}
+	want := ResultMatrix{{
+		Metric: map[string]string{"__name__": "up", "job": "node"},
+		Points: [][]any{{1719292597.171, "1"}, {1719292657.171, "0.5"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeReadResponse() = %#v, want %#v", got, want)
+	}
+}
+
+func TestDecodeReadResponse_Snappy(t *testing.T) {
+	b, err := hex.DecodeString(readResponseFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := snappyDecode(snappyEncode(b))
+	if err != nil {
+		t.Fatalf("snappyDecode() error = %v", err)
+	}
+	got, err := decodeReadResponse(decoded)
+	if err != nil {
+		t.Fatalf("decodeReadResponse() error = %v", err)
+	}
+	if len(got) != 1 || len(got[0].Points) != 2 {
+		t.Errorf("decodeReadResponse() = %#v, want one series of two samples", got)
+	}
+}
+
+func TestDecodeReadResponse_Invalid(t *testing.T) {
+	b, err := hex.DecodeString(readResponseFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, truncated := range [][]byte{b[:1], b[:10], b[:len(b)-1]} {
+		if got, err := decodeReadResponse(truncated); err == nil {
+			t.Errorf("decodeReadResponse(%x) = %#v, want an error", truncated, got)
+		}
+	}
+}
+
+func TestEncodeReadRequest(t *testing.T) {
+	matchers := []LabelMatcher{
+		{Name: "__name__", Op: "=", Value: "up"},
+		{Name: "job", Op: "=~", Value: "no.*"},
+	}
+	got := hex.EncodeToString(encodeReadRequest(matchers, time.UnixMilli(1000), time.UnixMilli(2000)))
+	if got != readRequestFixture {
+		t.Errorf("encodeReadRequest() = %s, want %s", got, readRequestFixture)
+	}
+}
@@ -0,0 +1,30 @@
+package promql
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// requestIDHeader identifies the request in the server logs. The retries of a request are sent with the same ID,
+// so that the server sees them as the attempts of one request.
+const requestIDHeader = "X-Request-Id"
+
+// withRequestID adds the request ID to the error message, so that the failed request can be found
+// in the server logs. Nil is returned for nil.
+func withRequestID(err error, id string) error {
+	if err == nil || id == "" {
+		return err
+	}
+	return fmt.Errorf("%w (request ID: %s)", err, id)
+}
+
+// newRequestID returns a random UUID (version 4).
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
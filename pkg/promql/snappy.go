@@ -0,0 +1,125 @@
+package promql
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// This file implements the snappy block format, which is required by the remote read protocol,
+// without depending on the snappy module. Only literals are emitted on encoding, which is valid though not compressed.
+// Format: https://github.com/google/snappy/blob/main/format_description.txt
+
+const snappyMaxLiteral = 1 << 16
+
+// snappyPreallocRatio bounds the buffer preallocated for the decoded data relative to the input size.
+// It's above the usual compression ratio of the remote read responses, so that the buffer rarely grows.
+const snappyPreallocRatio = 8
+
+var errSnappyCorrupt = errors.New("snappy: corrupt input")
+
+// snappyEncode encodes the input into the snappy block format.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > snappyMaxLiteral {
+			n = snappyMaxLiteral
+		}
+		switch m := n - 1; {
+		case m < 60:
+			dst = append(dst, byte(m)<<2)
+		case m < 1<<8:
+			dst = append(dst, 60<<2, byte(m))
+		default:
+			dst = append(dst, 61<<2, byte(m), byte(m>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
+
+// snappyDecode decodes the input in the snappy block format, rejecting the output longer than the header says.
+func snappyDecode(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > uint64(^uint32(0)) {
+		return nil, errSnappyCorrupt
+	}
+	src = src[n:]
+	limit := int(length)
+	dst := make([]byte, 0, min(length, uint64(len(src))*snappyPreallocRatio))
+
+	for len(src) > 0 {
+		tag := src[0]
+		src = src[1:]
+		switch tag & 0x03 {
+		case 0x00: // literal
+			m := int(tag >> 2)
+			if m >= 60 {
+				size := m - 59
+				if len(src) < size {
+					return nil, errSnappyCorrupt
+				}
+				m = 0
+				for i := size - 1; i >= 0; i-- {
+					m = m<<8 | int(src[i])
+				}
+				src = src[size:]
+			}
+			m++
+			if m <= 0 || len(src) < m || len(dst)+m > limit {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[:m]...)
+			src = src[m:]
+			continue
+		case 0x01: // copy with 1-byte offset
+			if len(src) < 1 {
+				return nil, errSnappyCorrupt
+			}
+			length := 4 + int(tag>>2)&0x07
+			offset := int(tag&0xe0)<<3 | int(src[0])
+			src = src[1:]
+			if err := snappyCopy(&dst, offset, length, limit); err != nil {
+				return nil, err
+			}
+		case 0x02: // copy with 2-byte offset
+			if len(src) < 2 {
+				return nil, errSnappyCorrupt
+			}
+			length := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint16(src))
+			src = src[2:]
+			if err := snappyCopy(&dst, offset, length, limit); err != nil {
+				return nil, err
+			}
+		case 0x03: // copy with 4-byte offset
+			if len(src) < 4 {
+				return nil, errSnappyCorrupt
+			}
+			length := 1 + int(tag>>2)
+			offset := int(binary.LittleEndian.Uint32(src))
+			src = src[4:]
+			if err := snappyCopy(&dst, offset, length, limit); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, errSnappyCorrupt
+	}
+	return dst, nil
+}
+
+// snappyCopy appends the bytes copied from offset bytes back. The ranges can overlap for repeated patterns.
+// An error is returned if the output would exceed the limit.
+func snappyCopy(dst *[]byte, offset, length, limit int) error {
+	if offset <= 0 || offset > len(*dst) || len(*dst)+length > limit {
+		return errSnappyCorrupt
+	}
+	start := len(*dst) - offset
+	for i := 0; i < length; i++ {
+		*dst = append(*dst, (*dst)[start+i])
+	}
+	return nil
+}
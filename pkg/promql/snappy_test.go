@@ -0,0 +1,89 @@
+package promql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnappyDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+		want string
+	}{
+		{
+			name: "empty",
+			src:  []byte{0x00},
+			want: "",
+		},
+		{
+			name: "literal",
+			src:  []byte{0x03, 0x08, 'a', 'b', 'c'},
+			want: "abc",
+		},
+		{
+			// A literal of 3 bytes followed by a copy of 9 bytes from 3 bytes back, which overlaps itself.
+			name: "copy with 1-byte offset",
+			src:  []byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x03},
+			want: "abcabcabcabc",
+		},
+		{
+			name: "copy with 2-byte offset",
+			src:  []byte{0x08, 0x0c, 'a', 'b', 'c', 'd', 0x0e, 0x04, 0x00},
+			want: "abcdabcd",
+		},
+		{
+			name: "copy with 4-byte offset",
+			src:  []byte{0x08, 0x0c, 'a', 'b', 'c', 'd', 0x0f, 0x04, 0x00, 0x00, 0x00},
+			want: "abcdabcd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snappyDecode(tt.src)
+			if err != nil {
+				t.Fatalf("snappyDecode() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("snappyDecode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnappyDecode_Corrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+	}{
+		{name: "no header", src: nil},
+		{name: "truncated literal", src: []byte{0x03, 0x08, 'a', 'b'}},
+		{name: "shorter than length", src: []byte{0x04, 0x08, 'a', 'b', 'c'}},
+		{name: "longer than length", src: []byte{0x02, 0x08, 'a', 'b', 'c'}},
+		{name: "copy before start", src: []byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x04}},
+		{name: "copy beyond length", src: []byte{0x06, 0x08, 'a', 'b', 'c', 0x15, 0x03}},
+		// The header claims 4 GiB, which must be rejected without allocating it.
+		{name: "huge length", src: []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x08, 'a', 'b', 'c'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := snappyDecode(tt.src); err == nil {
+				t.Errorf("snappyDecode() = %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 60, 61, 256, 257, snappyMaxLiteral, snappyMaxLiteral + 1, 3*snappyMaxLiteral + 5} {
+		src := []byte(strings.Repeat("promql", size/6+1)[:size])
+		got, err := snappyDecode(snappyEncode(src))
+		if err != nil {
+			t.Fatalf("size %d: snappyDecode() error = %v", size, err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("size %d: round trip = %d bytes, want %d bytes", size, len(got), len(src))
+		}
+	}
+}
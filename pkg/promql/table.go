@@ -0,0 +1,214 @@
+package promql
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Table is the tabular form of a result, which is written by the writers in this file.
+type Table struct {
+	Header []string
+	Rows   []Row
+}
+
+// Row is a row of the table, which has the columns in the same order as the header.
+type Row struct {
+	Columns []string
+}
+
+// WriteRawJSON writes the response body as returned by the server, or the response built by the client
+// with the object keys sorted.
+func WriteRawJSON(out io.Writer, resp *QueryResponse, indent bool) error {
+	body := resp.Body
+	if body == nil {
+		b, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		// The result is marshaled as-is from json.RawMessage, which may have the keys in any order.
+		if body, err = sortJSONKeys(b); err != nil {
+			return err
+		}
+	}
+	if indent {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+	if _, err := out.Write(body); err != nil {
+		return err
+	}
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		_, err := io.WriteString(out, "\n")
+		return err
+	}
+	return nil
+}
+
+// sortJSONKeys re-encodes the JSON with the object keys sorted, which encoding/json does for maps.
+// Numbers are kept as written so that timestamps and values aren't rounded.
+func sortJSONKeys(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// WriteTableJSON writes the table as a JSON array of objects keyed by the header, keeping the column order.
+// This is used for the results of commands, which don't have the response body of the Prometheus API.
+func WriteTableJSON(out io.Writer, table *Table, _ bool) error {
+	_, err := out.Write(append(tableJSON(table), '\n'))
+	return err
+}
+
+// WriteIndentedTableJSON writes the table same as WriteTableJSON, but indented with two spaces.
+func WriteIndentedTableJSON(out io.Writer, table *Table, _ bool) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, tableJSON(table), "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+func tableJSON(table *Table) []byte {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, row := range table.Rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("{")
+		for j, column := range row.Columns {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			name, _ := json.Marshal(table.Header[j])
+			value, _ := json.Marshal(column)
+			b.Write(name)
+			b.WriteString(":")
+			b.Write(value)
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("]")
+	return b.Bytes()
+}
+
+// WriteCSV writes the table as comma separated values. Values are quoted as needed by RFC 4180.
+func WriteCSV(out io.Writer, table *Table, noHeader bool) error {
+	w := csv.NewWriter(out)
+	if !noHeader {
+		if err := w.Write(table.Header); err != nil {
+			return err
+		}
+	}
+	for _, row := range table.Rows {
+		if err := w.Write(row.Columns); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteTSV writes the table as tab separated values.
+// Tabs, newlines, and backslashes in values are escaped with a backslash so that each row is always one line.
+func WriteTSV(out io.Writer, table *Table, noHeader bool) error {
+	if !noHeader {
+		if _, err := fmt.Fprintln(out, joinTSV(table.Header)); err != nil {
+			return err
+		}
+	}
+	for _, row := range table.Rows {
+		if _, err := fmt.Fprintln(out, joinTSV(row.Columns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func joinTSV(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = tsvEscaper.Replace(v)
+	}
+	return strings.Join(escaped, "\t")
+}
+
+// WriteMarkdown writes the table as a GitHub Flavored Markdown table.
+// The header row is always written since it's required by the Markdown table syntax.
+func WriteMarkdown(out io.Writer, table *Table, _ bool) error {
+	if _, err := fmt.Fprintln(out, joinMarkdown(table.Header)); err != nil {
+		return err
+	}
+	separators := make([]string, len(table.Header))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	if _, err := fmt.Fprintln(out, joinMarkdown(separators)); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		if _, err := fmt.Fprintln(out, joinMarkdown(row.Columns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", "<br>")
+
+func joinMarkdown(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = markdownEscaper.Replace(v)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// WriteExpanded writes each row as a block of "name: value" lines separated by a divider, like psql's expanded display.
+// This is more readable than a table for results with many labels.
+func WriteExpanded(out io.Writer, table *Table) error {
+	width := 0
+	for _, name := range table.Header {
+		if w := runewidth.StringWidth(name); w > width {
+			width = w
+		}
+	}
+
+	for i, row := range table.Rows {
+		if _, err := fmt.Fprintf(out, "-[ %d ]%s\n", i+1, strings.Repeat("-", width+4)); err != nil {
+			return err
+		}
+		for j, column := range row.Columns {
+			name := table.Header[j]
+			padding := strings.Repeat(" ", width-runewidth.StringWidth(name))
+			if _, err := fmt.Fprintf(out, "%s:%s %s\n", name, padding, column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package promql
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteRawJSON_KeepsBody(t *testing.T) {
+	// The keys are unsorted, and the numbers and the escapes are written in forms that re-encoding would change.
+	body := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a<b","__name__":"up"},"value":[1.719292597171E9,"1e+07"]}]}}`
+	resp, err := DecodeQueryResponse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRawJSON(&buf, resp, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), body+"\n"; got != want {
+		t.Errorf("WriteRawJSON() = %s, want %s", got, want)
+	}
+}
+
+func TestWriteRawJSON_SortsBuiltResponse(t *testing.T) {
+	resp := &QueryResponse{
+		Status: "success",
+		Data: Data{
+			ResultType: "vector",
+			ResultRaw:  json.RawMessage(`[{"metric":{"job":"a","instance":"i","__name__":"up"},"value":[1719292597.171,"1"]}]`),
+		},
+	}
+	want := `{"data":{"result":[{"metric":{"__name__":"up","instance":"i","job":"a"},"value":[1719292597.171,"1"]}],"resultType":"vector"},"error":"","infos":null,"status":"success","warnings":null}` + "\n"
+
+	// The output must be the same across runs, regardless of the iteration order of the maps.
+	for i := 0; i < 20; i++ {
+		var buf bytes.Buffer
+		if err := WriteRawJSON(&buf, resp, false); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("WriteRawJSON() = %s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package promql
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TransportOptions tunes the connection pooling of the HTTP transport.
+type TransportOptions struct {
+	// MaxIdleConns is the maximum number of idle (keep-alive) connections, which is also the limit per host.
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	DisableHTTP2    bool
+	// DisableCompression stops requesting gzip-compressed responses, which are decompressed transparently by default.
+	DisableCompression bool
+}
+
+// DefaultTransportOptions returns the options same as Go's default transport, except that MaxIdleConns also
+// applies per host.
+func DefaultTransportOptions() TransportOptions {
+	t := http.DefaultTransport.(*http.Transport)
+	return TransportOptions{
+		MaxIdleConns:    t.MaxIdleConns,
+		IdleConnTimeout: t.IdleConnTimeout,
+	}
+}
+
+func (o TransportOptions) newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = o.MaxIdleConns
+	t.MaxIdleConnsPerHost = o.MaxIdleConns
+	t.IdleConnTimeout = o.IdleConnTimeout
+	t.DisableCompression = o.DisableCompression
+	if o.DisableHTTP2 {
+		// A non-nil empty TLSNextProto disables HTTP/2.
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
+
+// responseBody returns the body of the response decompressed if needed. The transport decompresses gzip only if
+// it requested the compression by itself, so the response to Accept-Encoding given by the client headers is decompressed here.
+func responseBody(resp *http.Response) (io.Reader, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp.Body, nil
+	}
+	return gzip.NewReader(resp.Body)
+}
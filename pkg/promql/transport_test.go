@@ -0,0 +1,79 @@
+package promql
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newGzipServer returns the stub server which compresses the response if the request accepts gzip,
+// and records the Accept-Encoding header of the last request.
+func newGzipServer(t *testing.T, acceptEncoding *string) *httptest.Server {
+	t.Helper()
+	const body = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1719292597.171,"1"]}]}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(*acceptEncoding, "gzip") {
+			w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(body))
+		gz.Close()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQueryContext_Gzip(t *testing.T) {
+	tests := []struct {
+		name               string
+		headers            string
+		disableCompression bool
+		wantAccept         string
+	}{
+		{name: "compressed by default", wantAccept: "gzip"},
+		{name: "-no-compression", disableCompression: true, wantAccept: ""},
+		// The transport doesn't decompress the response to Accept-Encoding given by the headers, but the client does.
+		{name: "Accept-Encoding by -headers", headers: "Accept-Encoding: gzip", disableCompression: true, wantAccept: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acceptEncoding string
+			server := newGzipServer(t, &acceptEncoding)
+			opts := DefaultTransportOptions()
+			opts.DisableCompression = tt.disableCompression
+			client, err := NewClient(context.Background(), server.URL, "", tt.headers, opts, GCPOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.QueryContext(context.Background(), "up", QueryOptions{})
+			if err != nil {
+				t.Fatalf("QueryContext() error = %v", err)
+			}
+			if acceptEncoding != tt.wantAccept {
+				t.Errorf("Accept-Encoding = %q, want %q", acceptEncoding, tt.wantAccept)
+			}
+			want := ResultVector{{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.171, "1"}}}
+			if !reflect.DeepEqual(resp.Data.Result, want) {
+				t.Errorf("QueryContext() result = %#v, want %#v", resp.Data.Result, want)
+			}
+		})
+	}
+}
+
+func TestNewTransport_MaxIdleConnsPerHost(t *testing.T) {
+	opts := DefaultTransportOptions()
+	opts.MaxIdleConns = 10
+	// The client talks to only one server, so the limit applies per host instead of Go's default of 2.
+	if got := opts.newTransport().MaxIdleConnsPerHost; got != 10 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 10", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runProfileCommand runs `\profile <n> <query>`, which runs the query n times sequentially and shows the latency stats.
+// The results are discarded, and the query cache is not used. Ctrl-C aborts the run and shows the stats so far.
+func (c *CLI) runProfileCommand(ctx context.Context, args string) error {
+	usage := errors.New(`usage: \profile <n> <query>`)
+	count, query, ok := strings.Cut(args, " ")
+	if !ok {
+		return usage
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return usage
+	}
+	query = strings.TrimSpace(query)
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer cancel()
+
+	var latencies []time.Duration
+	var failures int
+	stop := c.PrintProgressingMark()
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		req, err := c.client.NewQueryRequest(query, c.queryOptions())
+		if err != nil {
+			stop()
+			return err
+		}
+		timing := newQueryTiming()
+		_, err = c.client.SendQueryRequest(req.WithContext(ctx))
+		timing.Stop()
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			failures++
+			continue
+		}
+		latencies = append(latencies, timing.Total)
+	}
+	stop()
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(c.out, "Aborted")
+	}
+	fmt.Fprintf(c.out, "%s\n\n", formatLatencyStats(latencies, failures))
+	return nil
+}
+
+// formatLatencyStats formats the percentiles of the latencies of the succeeded runs.
+func formatLatencyStats(latencies []time.Duration, failures int) string {
+	summary := fmt.Sprintf("%d runs", len(latencies)+failures)
+	if failures > 0 {
+		summary += fmt.Sprintf(" (%d failed)", failures)
+	}
+	if len(latencies) == 0 {
+		return summary
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	return fmt.Sprintf("%s: min=%s, p50=%s, p95=%s, max=%s, avg=%s", summary,
+		formatDuration(latencies[0]),
+		formatDuration(percentile(latencies, 50)),
+		formatDuration(percentile(latencies, 95)),
+		formatDuration(latencies[len(latencies)-1]),
+		formatDuration(total/time.Duration(len(latencies))))
+}
+
+// percentile returns the p-th percentile of the sorted latencies by the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// This file implements a lightweight PromQL lexer and parser for vector selectors,
+// which is enough to inspect and rewrite queries locally without depending on the whole Prometheus module.
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdentifier
+	tokenKeyword
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBrace
+	tokenRightBrace
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenComment
+)
+
+type token struct {
+	typ tokenType
+	val string
+	// pos is the byte offset of the token in the input.
+	pos int
+}
+
+// promqlKeywords are the identifiers which have special meaning in PromQL.
+var promqlKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "atan2": true,
+	"by": true, "without": true, "on": true, "ignoring": true,
+	"group_left": true, "group_right": true, "bool": true, "offset": true,
+	"start": true, "end": true,
+}
+
+var promqlDelimiters = map[rune]tokenType{
+	'(': tokenLeftParen, ')': tokenRightParen,
+	'{': tokenLeftBrace, '}': tokenRightBrace,
+	'[': tokenLeftBracket, ']': tokenRightBracket,
+	',': tokenComma,
+}
+
+// promqlOperators are sorted so that longer operators are matched first.
+var promqlOperators = []string{
+	"==", "!=", ">=", "<=", "=~", "!~",
+	"+", "-", "*", "/", "%", "^", ">", "<", "=", "@", ":",
+}
+
+// lexPromQL splits the query into tokens. Whitespace is skipped.
+func lexPromQL(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(input) {
+		r := rune(input[pos])
+		switch {
+		case unicode.IsSpace(r):
+			pos++
+		case r == '#':
+			end := strings.IndexByte(input[pos:], '\n')
+			if end < 0 {
+				end = len(input) - pos
+			}
+			tokens = append(tokens, token{typ: tokenComment, val: input[pos : pos+end], pos: pos})
+			pos += end
+		case r == '"' || r == '\'' || r == '`':
+			end, err := scanString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokenString, val: input[pos:end], pos: pos})
+			pos = end
+		case isDigit(r) || (r == '.' && pos+1 < len(input) && isDigit(rune(input[pos+1]))):
+			end, typ := scanNumberOrDuration(input, pos)
+			tokens = append(tokens, token{typ: typ, val: input[pos:end], pos: pos})
+			pos = end
+		case isIdentifierStart(r):
+			end := pos + 1
+			for end < len(input) && isIdentifierChar(rune(input[end])) {
+				end++
+			}
+			val := input[pos:end]
+			typ := tokenIdentifier
+			if promqlKeywords[strings.ToLower(val)] {
+				typ = tokenKeyword
+			}
+			tokens = append(tokens, token{typ: typ, val: val, pos: pos})
+			pos = end
+		default:
+			if typ, ok := promqlDelimiters[r]; ok {
+				tokens = append(tokens, token{typ: typ, val: string(r), pos: pos})
+				pos++
+				continue
+			}
+
+			var op string
+			for _, o := range promqlOperators {
+				if strings.HasPrefix(input[pos:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, pos)
+			}
+			tokens = append(tokens, token{typ: tokenOperator, val: op, pos: pos})
+			pos += len(op)
+		}
+	}
+	tokens = append(tokens, token{typ: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+// splitStatements splits the input into queries at the top-level `;`, and strips `#` comments.
+// Semicolons and `#` inside string literals, braces, brackets or parentheses are kept as-is.
+func splitStatements(input string) []string {
+	var statements []string
+	var b strings.Builder
+	depth := 0
+	flush := func() {
+		if statement := strings.TrimSpace(b.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		b.Reset()
+	}
+	for i := 0; i < len(input); i++ {
+		switch ch := input[i]; ch {
+		case '"', '\'', '`':
+			end, err := scanString(input, i)
+			if err != nil {
+				// Leave the unterminated string to the server to report the error.
+				end = len(input)
+			}
+			b.WriteString(input[i:end])
+			i = end - 1
+		case '#':
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				end = len(input) - i
+			}
+			i += end - 1
+		case '(', '{', '[':
+			depth++
+			b.WriteByte(ch)
+		case ')', '}', ']':
+			depth--
+			b.WriteByte(ch)
+		case ';':
+			if depth > 0 {
+				b.WriteByte(ch)
+				continue
+			}
+			flush()
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	flush()
+	return statements
+}
+
+// scanString returns the end offset of the quoted string starting at pos.
+func scanString(input string, pos int) (int, error) {
+	quote := input[pos]
+	for i := pos + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if quote != '`' {
+				i++ // skip the escaped character
+			}
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at position %d", pos)
+}
+
+// scanNumberOrDuration returns the end offset of the number or the duration (such as 5m or 1h30m) starting at pos.
+func scanNumberOrDuration(input string, pos int) (int, tokenType) {
+	end := pos
+	for end < len(input) && (isDigit(rune(input[end])) || input[end] == '.') {
+		end++
+	}
+
+	// Duration units, which can be combined like 1h30m.
+	if end < len(input) && strings.ContainsRune("smhdwy", rune(input[end])) {
+		for end < len(input) {
+			unitEnd := end
+			for unitEnd < len(input) && strings.ContainsRune("smhdwy", rune(input[unitEnd])) {
+				unitEnd++
+			}
+			if unitEnd == end {
+				break
+			}
+			end = unitEnd
+			for end < len(input) && isDigit(rune(input[end])) {
+				end++
+			}
+		}
+		return end, tokenDuration
+	}
+
+	// Exponent or hex digits.
+	for end < len(input) && (isIdentifierChar(rune(input[end])) ||
+		((input[end] == '+' || input[end] == '-') && (input[end-1] == 'e' || input[end-1] == 'E'))) {
+		end++
+	}
+	return end, tokenNumber
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == ':' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+func isIdentifierChar(r rune) bool {
+	return isIdentifierStart(r) || isDigit(r)
+}
+
+// labelMatcher is a label matcher in a vector selector, such as job="prometheus".
+type labelMatcher struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// vectorSelector is a parsed vector selector, such as up{job="prometheus"}.
+type vectorSelector struct {
+	MetricName string
+	Matchers   []labelMatcher
+}
+
+// parseVectorSelector parses the input as a single vector selector.
+// An error is returned if the input is any other expression.
+func parseVectorSelector(input string) (*vectorSelector, error) {
+	tokens, err := lexPromQL(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &selectorParser{tokens: tokens}
+	sel, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().typ != tokenEOF {
+		return nil, fmt.Errorf("not a vector selector: unexpected %q", p.peek().val)
+	}
+	return sel, nil
+}
+
+// parseBareSelector returns the vector selector part of the input if the input is a bare selector without
+// any function or aggregation, optionally with a range such as up[5m].
+func parseBareSelector(input string) (string, error) {
+	tokens, err := lexPromQL(input)
+	if err != nil {
+		return "", err
+	}
+	p := &selectorParser{tokens: tokens}
+	if _, err := p.parse(); err != nil {
+		return "", err
+	}
+	end := p.peek().pos
+	if p.peek().typ == tokenLeftBracket {
+		p.next()
+		if p.next().typ != tokenDuration || p.next().typ != tokenRightBracket {
+			return "", errors.New("not a bare selector: invalid range")
+		}
+	}
+	if p.peek().typ != tokenEOF {
+		return "", fmt.Errorf("not a bare selector: unexpected %q", p.peek().val)
+	}
+	return strings.TrimSpace(input[:end]), nil
+}
+
+type selectorParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *selectorParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *selectorParser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *selectorParser) parse() (*vectorSelector, error) {
+	var sel vectorSelector
+	if t := p.peek(); t.typ == tokenIdentifier {
+		sel.MetricName = p.next().val
+	}
+
+	if p.peek().typ == tokenLeftBrace {
+		p.next()
+		for p.peek().typ != tokenRightBrace {
+			name := p.next()
+			if name.typ != tokenIdentifier && name.typ != tokenKeyword {
+				return nil, fmt.Errorf("not a vector selector: expected label name, got %q", name.val)
+			}
+			op := p.next()
+			if op.typ != tokenOperator || !isMatchOperator(op.val) {
+				return nil, fmt.Errorf("not a vector selector: expected label matcher, got %q", op.val)
+			}
+			value := p.next()
+			if value.typ != tokenString {
+				return nil, fmt.Errorf("not a vector selector: expected label value, got %q", value.val)
+			}
+			sel.Matchers = append(sel.Matchers, labelMatcher{Name: name.val, Op: op.val, Value: unquote(value.val)})
+
+			if p.peek().typ == tokenComma {
+				p.next()
+				continue
+			}
+			if p.peek().typ != tokenRightBrace {
+				return nil, fmt.Errorf("not a vector selector: unexpected %q", p.peek().val)
+			}
+		}
+		p.next()
+	}
+
+	if sel.MetricName == "" && len(sel.Matchers) == 0 {
+		return nil, errors.New("not a vector selector: metric name or label matchers are required")
+	}
+	return &sel, nil
+}
+
+// isDuration returns true if the input is a PromQL duration such as 5m or 1h30m.
+func isDuration(input string) bool {
+	_, err := parsePromDuration(input)
+	return err == nil
+}
+
+// promqlDurationUnits are the units of PromQL durations. "ms" must precede "m" to be matched first.
+var promqlDurationUnits = []struct {
+	unit string
+	d    time.Duration
+}{
+	{"ms", time.Millisecond}, {"s", time.Second}, {"m", time.Minute}, {"h", time.Hour},
+	{"d", 24 * time.Hour}, {"w", 7 * 24 * time.Hour}, {"y", 365 * 24 * time.Hour},
+}
+
+// parsePromDuration parses the PromQL duration such as 5m, 1h30m or 30d, which time.ParseDuration doesn't accept.
+func parsePromDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("empty duration")
+	}
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && isDigit(rune(rest[i])) {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+		rest = rest[i:]
+
+		matched := false
+		for _, u := range promqlDurationUnits {
+			if strings.HasPrefix(rest, u.unit) {
+				total += time.Duration(n) * u.d
+				rest = rest[len(u.unit):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+	}
+	return total, nil
+}
+
+func isMatchOperator(op string) bool {
+	return op == "=" || op == "!=" || op == "=~" || op == "!~"
+}
+
+// unquote removes the quotes of the string literal and resolves backslash escapes.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	quote, body := s[0], s[1:len(s)-1]
+	if quote == '`' {
+		return body
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' && i+1 < len(body) {
+			i++
+			switch body[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(body[i])
+			}
+			continue
+		}
+		b.WriteByte(body[i])
+	}
+	return b.String()
+}
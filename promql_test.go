@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePromDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "5m", want: 5 * time.Minute},
+		{s: "1h30m", want: 90 * time.Minute},
+		{s: "500ms", want: 500 * time.Millisecond},
+		{s: "1d", want: 24 * time.Hour},
+		{s: "2w", want: 14 * 24 * time.Hour},
+		{s: "1y", want: 365 * 24 * time.Hour},
+		{s: "0s", want: 0},
+		{s: "", wantErr: true},
+		{s: "5", wantErr: true},
+		{s: "5mm", wantErr: true},
+		{s: "m5", wantErr: true},
+		{s: "5 m", wantErr: true},
+		{s: "-5m", wantErr: true},
+		{s: "1.5h", wantErr: true},
+		{s: "5M", wantErr: true},
+		{s: "10s;", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePromDuration(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePromDuration(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePromDuration(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+		if isDuration(tt.s) == tt.wantErr {
+			t.Errorf("isDuration(%q) = %v, want %v", tt.s, !tt.wantErr, !tt.wantErr)
+		}
+	}
+}
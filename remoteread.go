@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// defaultRemoteReadRange is the time range read for a selector without a range.
+const defaultRemoteReadRange = 5 * time.Minute
+
+// remoteReadQuery runs the selector with an optional range such as up[1h] by the remote read protocol.
+// The range ends at the pinned time if any.
+func (c *CLI) remoteReadQuery(ctx context.Context, q string) (*promql.QueryResponse, error) {
+	selector, err := parseBareSelector(q)
+	if err != nil {
+		return nil, fmt.Errorf("only a selector with an optional range is supported by remote read: %w", err)
+	}
+	readRange := defaultRemoteReadRange
+	if rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(q), selector)); rest != "" {
+		readRange, err = parsePromDuration(strings.Trim(rest, "[]"))
+		if err != nil || readRange <= 0 {
+			return nil, fmt.Errorf("invalid range: %q, expected a duration such as 1h or 7d", rest)
+		}
+	}
+
+	end := time.Now()
+	if !c.pinnedTime.IsZero() {
+		end = c.pinnedTime
+	}
+	sel, err := parseVectorSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.RemoteReadContext(ctx, remoteReadMatchers(sel), end.Add(-readRange), end)
+}
+
+// remoteReadMatchers returns the matchers of the selector, including the metric name as the __name__ matcher.
+func remoteReadMatchers(sel *vectorSelector) []promql.LabelMatcher {
+	var matchers []promql.LabelMatcher
+	if sel.MetricName != "" {
+		matchers = append(matchers, promql.LabelMatcher{Name: "__name__", Op: "=", Value: sel.MetricName})
+	}
+	for _, m := range sel.Matchers {
+		matchers = append(matchers, promql.LabelMatcher{Name: m.Name, Op: m.Op, Value: m.Value})
+	}
+	return matchers
+}
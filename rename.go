@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// labelRenameRule rewrites the values of the label matching the regexp at render time.
+type labelRenameRule struct {
+	label       string
+	re          *regexp.Regexp
+	replacement string
+}
+
+// runRenameCommand runs `\rename <label> <regex> [replacement]`, which adds a rename rule for label values.
+// `\rename` lists the rules, and `\rename clear` removes all of them.
+func (c *CLI) runRenameCommand(args string) error {
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		if len(c.tableOptions.RenameRules) == 0 {
+			fmt.Fprintf(c.out, "No rename rules\n\n")
+			return nil
+		}
+		for i, rule := range c.tableOptions.RenameRules {
+			fmt.Fprintf(c.out, "%d: %s %s %q\n", i+1, rule.label, rule.re, rule.replacement)
+		}
+		fmt.Fprintln(c.out)
+		return nil
+	case len(fields) == 1 && fields[0] == "clear":
+		c.tableOptions.RenameRules = nil
+		return nil
+	case len(fields) == 2 || len(fields) == 3:
+		re, err := regexp.Compile(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		rule := labelRenameRule{label: fields[0], re: re}
+		if len(fields) == 3 {
+			rule.replacement = fields[2]
+		}
+		c.tableOptions.RenameRules = append(c.tableOptions.RenameRules, rule)
+		return nil
+	default:
+		return errors.New(`usage: \rename <label> <regex> [replacement]`)
+	}
+}
+
+// renameLabelValue applies all the rename rules for the label in order, after trimming the prefix of the metric name.
+func (o *TableOptions) renameLabelValue(label, value string) string {
+	if label == "__name__" && o.TrimPrefix != "" {
+		value = o.trimMetricName(value)
+	}
+	for _, rule := range o.RenameRules {
+		if rule.label == label {
+			value = rule.re.ReplaceAllString(value, rule.replacement)
+		}
+	}
+	return value
+}
+
+// runRenameColumnCommand runs `\rename-column <label> <name>`, which shows the name in the header of the label column.
+func (c *CLI) runRenameColumnCommand(args string) error {
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		if len(c.tableOptions.ColumnNames) == 0 {
+			fmt.Fprintf(c.out, "No renamed columns\n\n")
+			return nil
+		}
+		labels := make([]string, 0, len(c.tableOptions.ColumnNames))
+		for label := range c.tableOptions.ColumnNames {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fmt.Fprintf(c.out, "%s: %s\n", label, c.tableOptions.ColumnNames[label])
+		}
+		fmt.Fprintln(c.out)
+		return nil
+	case len(fields) == 1 && fields[0] == "clear":
+		c.tableOptions.ColumnNames = nil
+		return nil
+	case len(fields) == 2 && fields[1] == "off":
+		delete(c.tableOptions.ColumnNames, fields[0])
+		return nil
+	case len(fields) == 2:
+		if c.tableOptions.ColumnNames == nil {
+			c.tableOptions.ColumnNames = make(map[string]string)
+		}
+		c.tableOptions.ColumnNames[fields[0]] = fields[1]
+		return nil
+	default:
+		return errors.New(`usage: \rename-column [<label> <name>|<label> off|clear]`)
+	}
+}
+
+// renameColumns replaces the header of the renamed columns. The JSON output keeps the label names as the keys,
+// so that it can be processed by other tools regardless of the renames.
+func (c *CLI) renameColumns(table *promql.Table) {
+	if c.format == formatRawJSON {
+		return
+	}
+	for i, header := range table.Header {
+		if name, ok := c.tableOptions.ColumnNames[header]; ok {
+			table.Header[i] = name
+		}
+	}
+}
+
+// labelValue returns the label value shown in the label column of the result table, which is renamed by the rules
+// and truncated to MaxWidth.
+func (o *TableOptions) labelValue(label, value string) string {
+	value = o.renameLabelValue(label, value)
+	if o.MaxWidth > 0 {
+		value = truncateWithEllipsis(value, o.MaxWidth)
+	}
+	return value
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// readResponseFile reads the response of the Query API saved in the file, such as the one recorded by -record.
+func readResponseFile(path string) (*promql.QueryResponse, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := promql.DecodeQueryResponse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return resp, nil
+}
+
+// RunReplay renders the response saved in the file without querying the server, and exits.
+func (c *CLI) RunReplay(path string) int {
+	resp, err := readResponseFile(path)
+	if err != nil {
+		return c.ExitOnError(err)
+	}
+	c.PrintResult(resp, "")
+	return exitCodeSuccess
+}
+
+// runReplayCommand runs `\replay <file>`, which renders the response saved in the file as the last result.
+func (c *CLI) runReplayCommand(args string) error {
+	if args == "" {
+		return errors.New(`usage: \replay <file>`)
+	}
+	resp, err := readResponseFile(args)
+	if err != nil {
+		return err
+	}
+	c.lastResponse = resp
+	c.PrintResult(resp, "")
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunReplay_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	// Well-formed JSON, but the timestamp is a string.
+	body := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":["1719292597.171","1"]}]}}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	c := &CLI{out: &out, errOut: &out, format: formatTable}
+	if code := c.RunReplay(path); code != exitCodeError {
+		t.Errorf("RunReplay() = %d, want %d", code, exitCodeError)
+	}
+	if !strings.Contains(out.String(), "invalid sample") {
+		t.Errorf("output = %q, want the decode error", out.String())
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// scrapeAcceptHeader asks for the text exposition format, which is also what targets return by default.
+const scrapeAcceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+
+// scrapedSample is a sample parsed from the exposition format. The value is kept as written by the target.
+type scrapedSample struct {
+	Name   string
+	Labels map[string]string
+	Value  string
+}
+
+// runScrapeCommand runs `\scrape <url>`, which fetches the metrics endpoint of a target directly and shows the samples.
+func (c *CLI) runScrapeCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \scrape <url>`)
+	}
+
+	stop := c.PrintProgressingMark()
+	samples, err := c.scrape(ctx, args)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	table := promql.Table{Header: []string{"metric", "labels", "value"}}
+	for _, sample := range samples {
+		var labels string
+		if len(sample.Labels) > 0 {
+			labels = formatMetric(sample.Labels)
+		}
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{sample.Name, labels, sample.Value}})
+	}
+	summary := fmt.Sprintf("%d samples scraped", len(samples))
+	if len(samples) == 0 {
+		summary = "No samples"
+	}
+	c.printTable(&table, summary)
+	return nil
+}
+
+// scrape fetches the metrics endpoint, and parses the response. The headers given by -headers aren't sent,
+// since they may be credentials for the server.
+func (c *CLI) scrape(ctx context.Context, target string) ([]scrapedSample, error) {
+	if timeout := c.client.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", scrapeAcceptHeader)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", target, resp.Status)
+	}
+	return parseExposition(resp.Body)
+}
+
+// parseExposition parses the samples in the text exposition format. HELP and TYPE lines are skipped,
+// as well as the timestamps and the OpenMetrics exemplars following the values.
+func parseExposition(r io.Reader) ([]scrapedSample, error) {
+	var samples []scrapedSample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseExpositionLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// parseExpositionLine parses the sample line such as `http_requests_total{code="200"} 1027 1395066363000`.
+func parseExpositionLine(line string) (scrapedSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end < 0 {
+		return scrapedSample{}, fmt.Errorf("no value: %s", line)
+	}
+	sample := scrapedSample{Name: line[:end]}
+	if !isMetricName(sample.Name) {
+		return scrapedSample{}, fmt.Errorf("invalid metric name: %q", sample.Name)
+	}
+	rest := line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseExpositionLabels(rest)
+		if err != nil {
+			return scrapedSample{}, err
+		}
+		sample.Labels = labels
+		rest = rest[n:]
+	}
+
+	// The value may be followed by the timestamp, and then the exemplar starting with `#` in OpenMetrics.
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return scrapedSample{}, fmt.Errorf("no value: %s", line)
+	}
+	if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+		return scrapedSample{}, fmt.Errorf("invalid value: %q", fields[0])
+	}
+	sample.Value = fields[0]
+	if len(fields) > 1 && fields[1] != "#" {
+		// The timestamp is in milliseconds in the text format, and in seconds with fractions in OpenMetrics.
+		if _, err := strconv.ParseFloat(fields[1], 64); err != nil {
+			return scrapedSample{}, fmt.Errorf("invalid timestamp: %q", fields[1])
+		}
+		if len(fields) > 2 && fields[2] != "#" {
+			return scrapedSample{}, fmt.Errorf("unexpected %q after the timestamp", fields[2])
+		}
+	}
+	return sample, nil
+}
+
+// parseExpositionLabels parses the label set starting with `{`, and returns it with the length of the consumed input.
+// Label values may contain the escaped backslash, double quote and newline, and the other escapes are invalid.
+func parseExpositionLabels(s string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, errors.New("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, errors.New("unterminated label set")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		if !isLabelName(name) {
+			return nil, 0, fmt.Errorf("invalid label name: %q", name)
+		}
+		if _, ok := labels[name]; ok {
+			return nil, 0, fmt.Errorf("duplicate label name: %q", name)
+		}
+		i += eq + 1
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label value of %q is not quoted", name)
+		}
+		i++
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case '\\', '"':
+					value.WriteByte(s[i])
+				default:
+					return nil, 0, fmt.Errorf("invalid escape sequence in the label value of %q: \\%c", name, s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label value of %q", name)
+		}
+		labels[name] = value.String()
+		i++
+
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		switch {
+		case i < len(s) && s[i] == ',':
+			i++
+		case i < len(s) && s[i] != '}':
+			return nil, 0, fmt.Errorf("unexpected %q after the label value of %q", s[i], name)
+		}
+	}
+}
+
+// isMetricName returns true if the string matches [a-zA-Z_:][a-zA-Z0-9_:]*.
+func isMetricName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || r == ':' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (i > 0 && '0' <= r && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isLabelName returns true if the string matches [a-zA-Z_][a-zA-Z0-9_]*.
+func isLabelName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (i > 0 && '0' <= r && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExposition(t *testing.T) {
+	const exposition = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+
+# Escapes in the label values.
+msdos_file_access_time_seconds{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9
+
+# Special values.
+metric_without_timestamp_and_labels 12.47
+something_weird{problem="division by zero"} +Inf -3982045
+nan_value NaN
+negative_inf{le="x", } -Inf
+
+# OpenMetrics timestamps in seconds and exemplars.
+foo_bucket{le="0.01"} 0 1520879607.789
+foo_bucket{le="0.1"} 8 # {trace_id="KOO5S4vxi0o"} 0.067
+foo_bucket{le="1"} 11 1520879607.789 # {trace_id="oHg5SJYRHA0", span_id="a b"} 0.5 1520879607.789
+`
+	got, err := parseExposition(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatalf("parseExposition() error = %v", err)
+	}
+	want := []scrapedSample{
+		{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "200"}, Value: "1027"},
+		{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "400"}, Value: "3"},
+		{Name: "msdos_file_access_time_seconds", Labels: map[string]string{"path": `C:\DIR\FILE.TXT`, "error": "Cannot find file:\n\"FILE.TXT\""}, Value: "1.458255915e9"},
+		{Name: "metric_without_timestamp_and_labels", Value: "12.47"},
+		{Name: "something_weird", Labels: map[string]string{"problem": "division by zero"}, Value: "+Inf"},
+		{Name: "nan_value", Value: "NaN"},
+		{Name: "negative_inf", Labels: map[string]string{"le": "x"}, Value: "-Inf"},
+		{Name: "foo_bucket", Labels: map[string]string{"le": "0.01"}, Value: "0"},
+		{Name: "foo_bucket", Labels: map[string]string{"le": "0.1"}, Value: "8"},
+		{Name: "foo_bucket", Labels: map[string]string{"le": "1"}, Value: "11"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseExposition() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestParseExposition_Malformed(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr string
+	}{
+		{line: `up`, wantErr: "no value"},
+		{line: `up{job="a"}`, wantErr: "no value"},
+		{line: `1up 1`, wantErr: "invalid metric name"},
+		{line: `up abc`, wantErr: "invalid value"},
+		{line: `up 1 abc`, wantErr: "invalid timestamp"},
+		{line: `up 1 2 3`, wantErr: "unexpected"},
+		{line: `up{="x"} 1`, wantErr: "invalid label name"},
+		{line: `up{1job="x"} 1`, wantErr: "invalid label name"},
+		{line: `up{job-name="x"} 1`, wantErr: "invalid label name"},
+		{line: `up{job="a",job="b"} 1`, wantErr: "duplicate label name"},
+		{line: `up{job=a} 1`, wantErr: "not quoted"},
+		{line: `up{job="a} 1`, wantErr: "unterminated label value"},
+		{line: `up{job="a" 1`, wantErr: "unexpected"},
+		{line: `up{job="a" instance="b"} 1`, wantErr: "unexpected"},
+		{line: `up{job="a\tb"} 1`, wantErr: "invalid escape sequence"},
+		{line: `up{job="a"`, wantErr: "unterminated label set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := parseExposition(strings.NewReader("# comment\n" + tt.line + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parseExposition() error = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), "line 2: ") {
+				t.Errorf("parseExposition() error = %v, want the line number", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const (
+	// seriesLookback is the time range to look up series, which is same as the default lookback delta of Prometheus.
+	seriesLookback = 5 * time.Minute
+
+	previewSampleSize = 5
+)
+
+// seriesTimeRange returns the time range to look up series, ending at the pinned time if any.
+func (c *CLI) seriesTimeRange() (time.Time, time.Time) {
+	end := time.Now()
+	if !c.pinnedTime.IsZero() {
+		end = c.pinnedTime
+	}
+	return end.Add(-seriesLookback), end
+}
+
+// runPreviewCommand runs `\preview <selector>`, which shows the number of series matching the selector
+// and a sample of their label sets without querying the values.
+func (c *CLI) runPreviewCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \preview <selector>`)
+	}
+	if _, err := parseVectorSelector(args); err != nil {
+		return err
+	}
+
+	start, end := c.seriesTimeRange()
+	stop := c.PrintProgressingMark()
+	series, err := c.client.SeriesContext(ctx, []string{args}, start, end)
+	stop()
+	if err != nil {
+		return err
+	}
+	if len(series) == 0 {
+		fmt.Fprintf(c.out, "No series\n\n")
+		return nil
+	}
+
+	sample := series
+	if len(sample) > previewSampleSize {
+		sample = sample[:previewSampleSize]
+	}
+	labelNames := c.tableOptions.visibleLabelNames(unionLabelNames(sample))
+	table := promql.Table{Header: labelNames}
+	for _, labels := range sample {
+		var row promql.Row
+		for _, labelName := range labelNames {
+			row.Columns = append(row.Columns, c.tableOptions.renameLabelValue(labelName, labels[labelName]))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	c.printTable(&table, fmt.Sprintf("~%d series, showing first %d", len(series), len(sample)))
+	return nil
+}
+
+// runLabelsOfCommand runs `\labels-of [-c] <selector>`, which shows the label names of the matching series.
+func (c *CLI) runLabelsOfCommand(ctx context.Context, args string) error {
+	var count bool
+	if option, rest, _ := strings.Cut(args, " "); option == "-c" {
+		count, args = true, strings.TrimSpace(rest)
+	}
+	if args == "" {
+		return errors.New(`usage: \labels-of [-c] <selector>`)
+	}
+	if _, err := parseVectorSelector(args); err != nil {
+		return err
+	}
+
+	stop := c.PrintProgressingMark()
+	table, err := c.labelsOf(ctx, args, count)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("%d labels", len(table.Rows))
+	if len(table.Rows) == 0 {
+		summary = "No labels"
+	}
+	c.printTable(table, summary)
+	return nil
+}
+
+// labelsOf returns the table of the sorted label names of the series matching the selector, with the number of
+// the values of each label if count is true.
+func (c *CLI) labelsOf(ctx context.Context, selector string, count bool) (*promql.Table, error) {
+	start, end := c.seriesTimeRange()
+	names, err := c.client.LabelNamesContext(ctx, []string{selector}, start, end)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	table := promql.Table{Header: []string{"label"}}
+	if count {
+		table.Header = append(table.Header, "values")
+	}
+	for _, name := range names {
+		row := promql.Row{Columns: []string{name}}
+		if count {
+			values, err := c.client.LabelValuesContext(ctx, name, []string{selector}, start, end)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			row.Columns = append(row.Columns, strconv.Itoa(len(values)))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return &table, nil
+}
+
+// runCountCommand runs `\count <query>`, which shows only the number of series in the result without rendering it.
+// For a range vector, the number of points is shown together.
+func (c *CLI) runCountCommand(ctx context.Context, args string) error {
+	if args == "" {
+		return errors.New(`usage: \count <query>`)
+	}
+
+	stop := c.PrintProgressingMark()
+	resp, _, err := c.query(ctx, args)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	switch result := resp.Data.Result.(type) {
+	case promql.ResultVector:
+		fmt.Fprintf(c.out, "%d series\n\n", len(result))
+	case promql.ResultMatrix:
+		var points int
+		for _, timeseries := range result {
+			points += len(timeseries.Points)
+		}
+		fmt.Fprintf(c.out, "%d series, %d points\n\n", len(result), points)
+	default:
+		fmt.Fprintf(c.out, "%s result\n\n", resp.Data.ResultType)
+	}
+	c.PrintAnnotations(resp)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// runSetCommand runs `\set <name> <value>`, which changes the session setting. `\set` lists the settings.
+func (c *CLI) runSetCommand(args string) error {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 0:
+		fmt.Fprintf(c.out, "align:     %s\n", c.align)
+		fmt.Fprintf(c.out, "autoclose: %s\n", onOff(c.autoClose))
+		fmt.Fprintf(c.out, "showtype:  %s\n\n", onOff(c.showType))
+		return nil
+	case 2:
+		switch fields[0] {
+		case "align":
+			if fields[1] != alignAuto && fields[1] != alignLeft {
+				return fmt.Errorf("expected %s or %s: %q", alignAuto, alignLeft, fields[1])
+			}
+			c.align = fields[1]
+			return nil
+		case "autoclose":
+			enabled, err := parseOnOff(fields[1])
+			if err != nil {
+				return err
+			}
+			c.autoClose = enabled
+			return nil
+		case "showtype":
+			enabled, err := parseOnOff(fields[1])
+			if err != nil {
+				return err
+			}
+			c.showType = enabled
+			return nil
+		default:
+			return fmt.Errorf("unknown setting: %q", fields[0])
+		}
+	default:
+		return errors.New(`usage: \set [<name> <value>]`)
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected on or off: %q", s)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Settings are the session settings persisted across sessions.
+// Only the settings about the presentation are persisted, and never the ones about authentication.
+type Settings struct {
+	Format        string `json:"format,omitempty"`
+	NoHeader      bool   `json:"no_header,omitempty"`
+	MaxWidth      int    `json:"max_width,omitempty"`
+	LookbackDelta string `json:"lookback_delta,omitempty"`
+	// Units are the units of the metrics set by `\unit`.
+	Units map[string]string `json:"units,omitempty"`
+}
+
+// settingsPath returns the path of the settings file in the user config directory.
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "promql-cli", "settings.json"), nil
+}
+
+// loadSettings loads the settings saved by the last session. Nil is returned if there are no saved settings.
+func loadSettings() (*Settings, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings Settings
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func saveSettings(settings *Settings) error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// applySettings overwrites the config with the saved settings, except for the ones explicitly given by flags.
+func applySettings(config *Config, settings *Settings, explicitFlags map[string]bool) {
+	if settings.Format != "" && isValidFormat(settings.Format) && !explicitFlags["format"] {
+		config.Format = settings.Format
+	}
+	if !explicitFlags["no-header"] {
+		config.NoHeader = settings.NoHeader
+	}
+	if !explicitFlags["max-width"] {
+		config.MaxWidth = settings.MaxWidth
+	}
+	if !explicitFlags["lookback-delta"] {
+		config.LookbackDelta = settings.LookbackDelta
+	}
+	config.Units = settings.Units
+}
+
+// changedSettings returns the saved settings updated with the ones changed in the session, so that the values
+// given by flags for this session aren't saved.
+func (c *CLI) changedSettings(saved *Settings) *Settings {
+	current := c.settings()
+	settings := *saved
+	if current.Format != c.initialSettings.Format {
+		settings.Format = current.Format
+	}
+	if current.NoHeader != c.initialSettings.NoHeader {
+		settings.NoHeader = current.NoHeader
+	}
+	if current.MaxWidth != c.initialSettings.MaxWidth {
+		settings.MaxWidth = current.MaxWidth
+	}
+	if current.LookbackDelta != c.initialSettings.LookbackDelta {
+		settings.LookbackDelta = current.LookbackDelta
+	}
+	settings.Units = current.Units
+	return &settings
+}
+
+// settings returns the current session settings.
+func (c *CLI) settings() *Settings {
+	return &Settings{
+		Format:        c.format,
+		NoHeader:      c.noHeader,
+		MaxWidth:      c.tableOptions.MaxWidth,
+		LookbackDelta: c.lookbackDelta,
+		Units:         c.tableOptions.Units,
+	}
+}
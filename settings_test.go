@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedSettings(t *testing.T) {
+	saved := &Settings{Format: formatTable, MaxWidth: 40, LookbackDelta: "10m"}
+	// The session started with -format csv -lookback-delta 1h.
+	c := &CLI{
+		format:          formatCSV,
+		lookbackDelta:   "1h",
+		initialSettings: &Settings{Format: formatCSV, MaxWidth: 40, LookbackDelta: "1h"},
+		tableOptions:    TableOptions{MaxWidth: 40},
+	}
+	if got, want := c.changedSettings(saved), saved; !reflect.DeepEqual(got, want) {
+		t.Errorf("changedSettings() without changes = %+v, want %+v", got, want)
+	}
+
+	c.format = formatMarkdown
+	c.noHeader = true
+	c.tableOptions.MaxWidth = 20
+	c.tableOptions.Units = map[string]string{"x_bytes": unitBytes}
+	want := &Settings{Format: formatMarkdown, NoHeader: true, MaxWidth: 20, LookbackDelta: "10m", Units: map[string]string{"x_bytes": unitBytes}}
+	if got := c.changedSettings(saved); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedSettings() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runBuildInfoCommand runs `\buildinfo`, which shows the build information of the server.
+func (c *CLI) runBuildInfoCommand(ctx context.Context) error {
+	return c.printStatusInfo(ctx, c.client.BuildInfoContext)
+}
+
+// runRuntimeInfoCommand runs `\runtimeinfo`, which shows the runtime information of the server.
+func (c *CLI) runRuntimeInfoCommand(ctx context.Context) error {
+	return c.printStatusInfo(ctx, c.client.RuntimeInfoContext)
+}
+
+// printStatusInfo fetches the fields from the status API, and prints them as a key/value table.
+func (c *CLI) printStatusInfo(ctx context.Context, fetch func(context.Context) (map[string]json.RawMessage, error)) error {
+	stop := c.PrintProgressingMark()
+	info, err := fetch(ctx)
+	stop()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(info))
+	for key := range info {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	table := promql.Table{Header: []string{"name", "value"}}
+	for _, key := range keys {
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{key, formatJSONValue(info[key])}})
+	}
+	c.printTable(&table, "")
+	return nil
+}
+
+// formatJSONValue formats the JSON value for display. Strings are shown without quotes,
+// and other values such as numbers and booleans are shown as-is.
+func formatJSONValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,30 @@
+package main
+
+import "strconv"
+
+// Query parameters supported by Thanos Querier (and compatible backends). Vanilla Prometheus ignores them.
+const (
+	dedupParam           = "dedup"
+	partialResponseParam = "partial_response"
+)
+
+// queryParamFlag implements flag.Value for a boolean query parameter which is sent only when the flag is given,
+// so that the server default is used otherwise. Both `-flag` and `-flag=false` are accepted.
+type queryParamFlag string
+
+func (f *queryParamFlag) String() string {
+	return string(*f)
+}
+
+func (f *queryParamFlag) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*f = queryParamFlag(strconv.FormatBool(v))
+	return nil
+}
+
+func (f *queryParamFlag) IsBoolFlag() bool {
+	return true
+}
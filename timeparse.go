@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts accepted by parseTime in addition to RFC3339. Times without a time zone are in the local time zone.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// unixMillisThreshold distinguishes Unix timestamps in milliseconds from ones in seconds.
+// Timestamps in seconds won't reach this value until the year 33658.
+const unixMillisThreshold = 1e12
+
+// parseTime parses the time given by users. The following forms are accepted:
+//   - now
+//   - Duration relative to now, such as -5m, -1h30m or -1d
+//   - Unix timestamp in seconds or milliseconds, such as 1719292597 or 1719292597171
+//   - RFC3339, such as 2024-01-02T03:04:05Z
+//   - Date and time without time zone, such as 2024-01-02T03:04 (local time zone)
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	now := time.Now()
+
+	if s == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		// PromQL durations such as -1d are accepted, as well as Go's such as -1.5h.
+		d, err := parsePromDuration(s[1:])
+		if err != nil {
+			if d, err = time.ParseDuration(s[1:]); err != nil {
+				return time.Time{}, fmt.Errorf("invalid relative time %q: expected a duration such as -5m or -1d", s)
+			}
+		}
+		if s[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		if n >= unixMillisThreshold {
+			return time.UnixMilli(int64(n)), nil
+		}
+		return time.UnixMilli(int64(n * 1000)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected now, relative duration (e.g. -5m), Unix timestamp, or RFC3339", s)
+}
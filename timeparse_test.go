@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime_Relative(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "-5m", want: -5 * time.Minute},
+		{s: "-1h30m", want: -90 * time.Minute},
+		{s: "-1d", want: -24 * time.Hour},
+		{s: "-1w", want: -7 * 24 * time.Hour},
+		{s: "+2h", want: 2 * time.Hour},
+		{s: "-1.5h", want: -90 * time.Minute},
+		{s: "-5mm", wantErr: true},
+		{s: "-", wantErr: true},
+		{s: "-d", wantErr: true},
+	}
+	for _, tt := range tests {
+		before := time.Now()
+		got, err := parseTime(tt.s)
+		after := time.Now()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTime(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.Before(before.Add(tt.want)) || got.After(after.Add(tt.want))) {
+			t.Errorf("parseTime(%q) = %v, want now%+v", tt.s, got, tt.want)
+		}
+	}
+}
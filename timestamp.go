@@ -0,0 +1,48 @@
+package main
+
+import "strconv"
+
+// Formats of the timestamp column.
+const (
+	timestampFormatRFC3339 = "rfc3339"
+	// timestampFormatEpoch shows the timestamp in Unix seconds as returned by the API.
+	timestampFormatEpoch = "epoch"
+	// timestampFormatBoth shows the epoch column next to the RFC3339 timestamp column.
+	timestampFormatBoth = "both"
+)
+
+var timestampFormats = []string{timestampFormatRFC3339, timestampFormatEpoch, timestampFormatBoth}
+
+func isValidTimestampFormat(format string) bool {
+	for _, f := range timestampFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// timestampHeader returns the header of the timestamp columns.
+func (o *TableOptions) timestampHeader() []string {
+	switch o.TimestampFormat {
+	case timestampFormatEpoch:
+		return []string{"epoch"}
+	case timestampFormatBoth:
+		return []string{"timestamp", "epoch"}
+	default:
+		return []string{"timestamp"}
+	}
+}
+
+// timestampColumns returns the timestamp columns for the timestamp in float seconds.
+func (o *TableOptions) timestampColumns(timestamp float64) []string {
+	epoch := strconv.FormatFloat(timestamp, 'f', -1, 64)
+	switch o.TimestampFormat {
+	case timestampFormatEpoch:
+		return []string{epoch}
+	case timestampFormatBoth:
+		return []string{formatTimestamp(timestamp), epoch}
+	default:
+		return []string{formatTimestamp(timestamp)}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"time"
+)
+
+const (
+	timingModeOff      = ""
+	timingModeSimple   = "true"
+	timingModeDetailed = "detailed"
+)
+
+// timingFlag implements flag.Value so that both `-timing` and `-timing=detailed` are accepted.
+type timingFlag string
+
+func (f *timingFlag) String() string {
+	return string(*f)
+}
+
+func (f *timingFlag) Set(s string) error {
+	switch s {
+	case "true", "1":
+		*f = timingModeSimple
+	case "false", "0":
+		*f = timingModeOff
+	case timingModeDetailed:
+		*f = timingModeDetailed
+	default:
+		return fmt.Errorf("invalid timing mode: %q", s)
+	}
+	return nil
+}
+
+func (f *timingFlag) IsBoolFlag() bool {
+	return true
+}
+
+// QueryTiming holds the client-side latency of a single query.
+// DNS, Connect, and TTFB are only populated when the request is traced by ClientTrace.
+type QueryTiming struct {
+	Total   time.Duration
+	DNS     time.Duration
+	Connect time.Duration
+	TTFB    time.Duration
+	// RequestID is shown with the latency so that a slow query can be found in the server logs.
+	RequestID string
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+}
+
+func newQueryTiming() *QueryTiming {
+	return &QueryTiming{start: time.Now()}
+}
+
+// ClientTrace returns an httptrace.ClientTrace that records DNS, connect (including TLS handshake), and TTFB latency.
+func (t *QueryTiming) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.Connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.Connect = time.Since(t.connectStart)
+		},
+		GotFirstResponseByte: func() {
+			t.TTFB = time.Since(t.start)
+		},
+	}
+}
+
+func (t *QueryTiming) Stop() {
+	t.Total = time.Since(t.start)
+}
+
+func (t *QueryTiming) Format(detailed bool) string {
+	var requestID string
+	if t.RequestID != "" {
+		requestID = ", request ID: " + t.RequestID
+	}
+	if !detailed {
+		return fmt.Sprintf("(%s%s)", formatDuration(t.Total), requestID)
+	}
+	return fmt.Sprintf("(%s: dns=%s, connect=%s, ttfb=%s%s)",
+		formatDuration(t.Total), formatDuration(t.DNS), formatDuration(t.Connect), formatDuration(t.TTFB), requestID)
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runTrimCommand runs `\trim <prefix>`, which strips the common prefix from the metric names in the output,
+// same as -trim-prefix. `\trim` shows the prefix, and `\trim clear` shows the full names again.
+func (c *CLI) runTrimCommand(args string) error {
+	switch args {
+	case "":
+		if c.tableOptions.TrimPrefix == "" {
+			fmt.Fprintf(c.out, "No prefix is trimmed\n\n")
+			return nil
+		}
+		fmt.Fprintf(c.out, "%s\n\n", c.tableOptions.TrimPrefix)
+	case "clear":
+		c.tableOptions.TrimPrefix = ""
+	default:
+		c.tableOptions.TrimPrefix = args
+	}
+	return nil
+}
+
+// trimMetricName strips the prefix from the metric name. The name is kept as-is if it would be empty.
+func (o *TableOptions) trimMetricName(name string) string {
+	if trimmed := strings.TrimPrefix(name, o.TrimPrefix); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runTSDBCommand runs `\tsdb`, which shows the cardinality statistics of the TSDB head block.
+func (c *CLI) runTSDBCommand(ctx context.Context) error {
+	stop := c.PrintProgressingMark()
+	status, err := c.client.TSDBStatusContext(ctx)
+	stop()
+	if err != nil {
+		return err
+	}
+	if c.format == formatRawJSON {
+		return c.writeJSON(status)
+	}
+
+	head := status.HeadStats
+	c.printTable(&promql.Table{
+		Header: []string{"head", "value"},
+		Rows: []promql.Row{
+			{Columns: []string{"series", strconv.FormatUint(head.NumSeries, 10)}},
+			{Columns: []string{"label pairs", strconv.Itoa(head.NumLabelPairs)}},
+			{Columns: []string{"chunks", strconv.FormatInt(head.ChunkCount, 10)}},
+			{Columns: []string{"min time", formatTimestamp(float64(head.MinTime) / 1000)}},
+			{Columns: []string{"max time", formatTimestamp(float64(head.MaxTime) / 1000)}},
+		},
+	}, "")
+
+	// The series count by metric name is always sorted by the count.
+	byMetricName := append([]promql.TSDBStat(nil), status.SeriesCountByMetricName...)
+	sort.SliceStable(byMetricName, func(i, j int) bool {
+		return byMetricName[i].Value > byMetricName[j].Value
+	})
+	for _, table := range []*promql.Table{
+		buildTSDBStatTable("metric name", "series", byMetricName),
+		buildTSDBStatTable("label name", "values", status.LabelValueCountByLabelName),
+		buildTSDBStatTable("label name", "memory bytes", status.MemoryInBytesByLabelName),
+		buildTSDBStatTable("label pair", "series", status.SeriesCountByLabelValuePair),
+	} {
+		if len(table.Rows) > 0 {
+			c.printTable(table, "")
+		}
+	}
+	return nil
+}
+
+// writeJSON writes the value as JSON, indented same as the raw-json output.
+func (c *CLI) writeJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if c.prettyJSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", "  "); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	_, err = fmt.Fprintf(c.out, "%s\n", b)
+	return err
+}
+
+func buildTSDBStatTable(name, value string, stats []promql.TSDBStat) *promql.Table {
+	table := promql.Table{Header: []string{name, value}}
+	for _, stat := range stats {
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{stat.Name, strconv.FormatUint(stat.Value, 10)}})
+	}
+	return &table
+}
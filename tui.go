@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+	"github.com/mattn/go-runewidth"
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+const (
+	enterAltScreen = "\033[?1049h\033[?25l"
+	exitAltScreen  = "\033[?25h\033[?1049l"
+
+	browserHelp           = "q:quit j/k:scroll h/l:left/right space/b:page g/G:top/bottom /:search n/N:next/prev s:sort r:reverse"
+	browserHorizontalStep = 8
+)
+
+// browserKeys maps the input from the terminal to the actions of the browser.
+var browserKeys = map[string]string{
+	"k": "up", "\033[A": "up", "\033OA": "up",
+	"j": "down", "\033[B": "down", "\033OB": "down", "\r": "down",
+	"h": "left", "\033[D": "left", "\033OD": "left",
+	"l": "right", "\033[C": "right", "\033OC": "right",
+	"b": "pageup", "\033[5~": "pageup",
+	" ": "pagedown", "f": "pagedown", "\033[6~": "pagedown",
+	"g": "top", "\033[H": "top", "\033[1~": "top",
+	"G": "bottom", "\033[F": "bottom", "\033[4~": "bottom",
+	"/": "search", "n": "next", "N": "prev",
+	"s": "sort", "r": "reverse",
+	"q": "quit", "\033": "quit", "\x03": "quit",
+}
+
+// browserTerminal returns the input and the output terminals for the browser, or false if either isn't a terminal.
+func (c *CLI) browserTerminal() (*os.File, *os.File, bool) {
+	in, ok := c.in.(*os.File)
+	if !ok || !readline.IsTerminal(int(in.Fd())) {
+		return nil, nil, false
+	}
+	out, ok := unwrapFile(c.out)
+	if !ok || !readline.IsTerminal(int(out.Fd())) {
+		return nil, nil, false
+	}
+	return in, out, true
+}
+
+// tableBrowser is the state of the scrollable viewport of a table shown by `-tui`.
+type tableBrowser struct {
+	cli   *CLI
+	table *promql.Table
+	rows  []promql.Row
+
+	// header is the rendered lines kept at the top, and body is the rendered lines of the rows followed by the bottom border.
+	header []string
+	body   []string
+
+	top  int
+	left int
+	// sortColumn is the index of the column which the rows are sorted by, or -1 for the original order.
+	sortColumn int
+	sortDesc   bool
+	// match is the index of the row found by the search, or -1.
+	match   int
+	pattern string
+	message string
+}
+
+// browseTable shows the table on the alternate screen until the user quits, so that the large result can be
+// scrolled in both directions without a pager.
+func (c *CLI) browseTable(table *promql.Table, in, out *os.File) error {
+	state, err := readline.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer readline.Restore(int(in.Fd()), state)
+	fmt.Fprint(out, enterAltScreen)
+	defer fmt.Fprint(out, exitAltScreen)
+
+	b := &tableBrowser{cli: c, table: table, sortColumn: -1, match: -1}
+	b.sortRows()
+
+	buf := make([]byte, 16)
+	for {
+		width, height, err := readline.GetSize(int(out.Fd()))
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, b.draw(width, height))
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+		switch action := browserKeys[string(buf[:n])]; action {
+		case "quit":
+			return nil
+		case "search":
+			pattern, ok, err := b.readPattern(in, out, width, height)
+			if err != nil {
+				return err
+			}
+			if ok && pattern != "" {
+				b.pattern = pattern
+				b.find(b.top, 1, b.visibleRows(height))
+			}
+		case "next":
+			b.find(b.match+1, 1, b.visibleRows(height))
+		case "prev":
+			b.find(b.match-1, -1, b.visibleRows(height))
+		case "sort":
+			// Cycle the sort column through all the columns and the original order.
+			b.sortColumn++
+			if b.sortColumn >= len(table.Header) {
+				b.sortColumn = -1
+			}
+			b.sortRows()
+		case "reverse":
+			b.sortDesc = !b.sortDesc
+			b.sortRows()
+		default:
+			b.scroll(action, width, height)
+		}
+	}
+}
+
+// sortRows sorts the rows by the sort column, and renders them. Numbers are compared by compareSampleValues.
+func (b *tableBrowser) sortRows() {
+	b.rows = append(b.rows[:0], b.table.Rows...)
+	if b.sortColumn >= 0 {
+		i := b.sortColumn
+		sort.SliceStable(b.rows, func(x, y int) bool {
+			if b.sortDesc {
+				x, y = y, x
+			}
+			return compareCells(b.rows[x].Columns[i], b.rows[y].Columns[i]) < 0
+		})
+	} else if b.sortDesc {
+		for x, y := 0, len(b.rows)-1; x < y; x, y = x+1, y-1 {
+			b.rows[x], b.rows[y] = b.rows[y], b.rows[x]
+		}
+	}
+
+	var buf bytes.Buffer
+	b.cli.writeTable(&buf, &promql.Table{Header: b.table.Header, Rows: b.rows})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	// The header is the top border, the column names and the separator, or only the top border with -no-header.
+	frozen := 3
+	if b.cli.noHeader {
+		frozen = 1
+	}
+	b.header, b.body = lines[:frozen], lines[frozen:]
+	b.match = -1
+}
+
+func compareCells(x, y string) int {
+	fx, okX := parseSampleValue(x)
+	fy, okY := parseSampleValue(y)
+	if okX && okY {
+		return compareSampleValues(fx, fy)
+	}
+	return strings.Compare(x, y)
+}
+
+func (b *tableBrowser) visibleRows(height int) int {
+	// The last line is for the status.
+	return max(height-len(b.header)-1, 1)
+}
+
+func (b *tableBrowser) scroll(action string, width, height int) {
+	visible := b.visibleRows(height)
+	switch action {
+	case "up":
+		b.top--
+	case "down":
+		b.top++
+	case "pageup":
+		b.top -= visible
+	case "pagedown":
+		b.top += visible
+	case "top":
+		b.top = 0
+	case "bottom":
+		b.top = len(b.body)
+	case "left":
+		b.left -= browserHorizontalStep
+	case "right":
+		b.left += browserHorizontalStep
+	}
+	b.clamp(width, height)
+}
+
+func (b *tableBrowser) clamp(width, height int) {
+	b.top = max(min(b.top, len(b.body)-b.visibleRows(height)), 0)
+	maxWidth := 0
+	for _, line := range b.header {
+		maxWidth = max(maxWidth, displayWidth(line))
+	}
+	b.left = max(min(b.left, maxWidth-width), 0)
+}
+
+// find searches the rows from the start in the direction for the pattern case-insensitively, wrapping around the ends.
+func (b *tableBrowser) find(start, step, visible int) {
+	b.message = ""
+	if b.pattern == "" {
+		return
+	}
+	pattern := strings.ToLower(b.pattern)
+	for i := 0; i < len(b.rows); i++ {
+		row := ((start+i*step)%len(b.rows) + len(b.rows)) % len(b.rows)
+		for _, column := range b.rows[row].Columns {
+			if strings.Contains(strings.ToLower(column), pattern) {
+				b.match = row
+				if row < b.top || row >= b.top+visible {
+					b.top = row
+				}
+				return
+			}
+		}
+	}
+	b.match = -1
+	b.message = fmt.Sprintf("pattern not found: %s", b.pattern)
+}
+
+// readPattern reads the search pattern on the status line. False is returned if the search is canceled.
+func (b *tableBrowser) readPattern(in, out *os.File, width, height int) (string, bool, error) {
+	var pattern []rune
+	buf := make([]byte, 16)
+	for {
+		fmt.Fprintf(out, "\033[%d;1H\033[K/%s", height, sliceWidth(string(pattern), 0, width-1))
+		n, err := in.Read(buf)
+		if err != nil {
+			return "", false, err
+		}
+		switch input := string(buf[:n]); input {
+		case "\r", "\n":
+			return string(pattern), true, nil
+		case "\033", "\x03":
+			return "", false, nil
+		case "\x7f", "\b":
+			if len(pattern) > 0 {
+				pattern = pattern[:len(pattern)-1]
+			}
+		default:
+			if !strings.HasPrefix(input, "\033") {
+				for _, r := range input {
+					if r >= ' ' {
+						pattern = append(pattern, r)
+					}
+				}
+			}
+		}
+	}
+}
+
+// draw returns the screen of the browser fitted in the terminal size.
+func (b *tableBrowser) draw(width, height int) string {
+	b.clamp(width, height)
+	var s strings.Builder
+	s.WriteString("\033[H")
+	for _, line := range b.header {
+		s.WriteString(sliceWidth(line, b.left, width) + "\033[K\r\n")
+	}
+	visible := b.visibleRows(height)
+	for i := b.top; i < b.top+visible; i++ {
+		if i < len(b.body) {
+			line := sliceWidth(b.body[i], b.left, width)
+			if i == b.match {
+				line = colorReverse + line + colorReset
+			}
+			s.WriteString(line)
+		}
+		s.WriteString("\033[K\r\n")
+	}
+
+	status := fmt.Sprintf(" rows %d-%d of %d", min(b.top+1, len(b.rows)), min(b.top+visible, len(b.rows)), len(b.rows))
+	if b.sortColumn >= 0 {
+		status += fmt.Sprintf(", sorted by %s", b.table.Header[b.sortColumn])
+	}
+	if b.sortDesc {
+		status += " (reversed)"
+	}
+	if b.message != "" {
+		status += " | " + b.message
+	} else {
+		status += " | " + browserHelp
+	}
+	status = sliceWidth(status, 0, width)
+	status += strings.Repeat(" ", width-displayWidth(status))
+	s.WriteString(colorReverse + status + colorReset)
+	return s.String()
+}
+
+// sliceWidth returns the part of the string from the display column, which fits in the width.
+func sliceWidth(s string, from, width int) string {
+	var b strings.Builder
+	x := 0
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		w := runewidth.RuneWidth(r)
+		switch {
+		case x >= from+width:
+		case x >= from && x+w <= from+width:
+			b.WriteRune(r)
+		case x < from && x+w > from:
+			b.WriteString(strings.Repeat(" ", min(x+w, from+width)-from))
+		}
+		x += w
+	}
+	return b.String()
+}
+
+// ansiSequenceLen returns the length of the CSI sequence such as "\033[1;31m" at the beginning of the string,
+// or zero if it doesn't begin with one.
+func ansiSequenceLen(s string) int {
+	if !strings.HasPrefix(s, "\033[") {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// displayWidth returns the number of columns the string takes in the terminal, excluding ANSI escape sequences.
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := ansiSequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width += runewidth.RuneWidth(r)
+	}
+	return width
+}
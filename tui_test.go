@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestSliceWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		from  int
+		width int
+		want  string
+	}{
+		{s: "abcdef", from: 2, width: 3, want: "cde"},
+		{s: "東京都", from: 0, width: 4, want: "東京"},
+		{s: "東京都", from: 2, width: 4, want: "京都"},
+		// The wide character split by the left edge is replaced with a space.
+		{s: "東京都", from: 1, width: 4, want: " 京"},
+		{s: "東京都", from: 1, width: 5, want: " 京都"},
+		// The one split by the right edge is dropped.
+		{s: "東京都", from: 0, width: 3, want: "東"},
+		{s: "a東b", from: 2, width: 2, want: " b"},
+		{s: "\033[1;31m東京\033[0m都", from: 2, width: 2, want: "\033[1;31m京\033[0m"},
+		{s: "東京", from: 4, width: 2, want: ""},
+	}
+	for _, tt := range tests {
+		if got := sliceWidth(tt.s, tt.from, tt.width); got != tt.want {
+			t.Errorf("sliceWidth(%q, %d, %d) = %q, want %q", tt.s, tt.from, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "up", want: 2},
+		{s: "東京都", want: 6},
+		{s: "job=東京", want: 8},
+		{s: "🔥", want: 2},
+		{s: "\033[1;31m東京\033[0m", want: 4},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runUnionCommand runs `\union <query1> | <query2> | ...`, which stacks the results of the queries into one table.
+func (c *CLI) runUnionCommand(ctx context.Context, args string) error {
+	queries, ok := splitQueries(args)
+	if !ok || len(queries) < 2 {
+		return errors.New(`usage: \union <query1> | <query2> | ...`)
+	}
+
+	resps := make([]*promql.QueryResponse, len(queries))
+	stop := c.PrintProgressingMark()
+	for i, query := range queries {
+		resp, _, err := c.query(ctx, query)
+		if err != nil {
+			stop()
+			return fmt.Errorf("%s: %w", query, err)
+		}
+		resps[i] = resp
+	}
+	stop()
+
+	table, err := buildUnionTable(queries, resps, &c.tableOptions)
+	if err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("%d values in result", len(table.Rows))
+	if len(table.Rows) == 0 {
+		summary = "Empty result"
+	}
+	c.printTable(table, summary)
+	for _, resp := range resps {
+		c.PrintAnnotations(resp)
+	}
+	return nil
+}
+
+// buildUnionTable builds the table stacking the vector results with the query column.
+func buildUnionTable(queries []string, resps []*promql.QueryResponse, opts *TableOptions) (*promql.Table, error) {
+	vectors := make([]promql.ResultVector, len(resps))
+	var metrics []map[string]string
+	for i, resp := range resps {
+		switch result := resp.Data.Result.(type) {
+		case promql.ResultVector:
+			vectors[i] = result
+		case promql.ResultScalar:
+			vectors[i] = promql.ResultVector{{Metric: map[string]string{}, Point: result}}
+		default:
+			return nil, fmt.Errorf("%s: unsupported result type: %q", queries[i], resp.Data.ResultType)
+		}
+		for _, timeseries := range vectors[i] {
+			metrics = append(metrics, timeseries.Metric)
+		}
+	}
+
+	labelNames := opts.visibleLabelNames(unionLabelNames(metrics))
+	table := promql.Table{Header: append(opts.timestampHeader(), "query")}
+	table.Header = append(table.Header, labelNames...)
+	table.Header = append(table.Header, "value")
+	for i, vector := range vectors {
+		for _, timeseries := range vector {
+			row := promql.Row{Columns: append(opts.timestampColumns(timeseries.Point[0].(float64)), queries[i])}
+			for _, labelName := range labelNames {
+				row.Columns = append(row.Columns, opts.labelValue(labelName, timeseries.Metric[labelName]))
+			}
+			row.Columns = append(row.Columns, opts.formatValue(timeseries.Metric, timeseries.Point[1].(string)))
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	if opts.BoolMarkers {
+		replaceBoolValues(&table)
+	}
+	return &table, nil
+}
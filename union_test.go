@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+func TestBuildUnionTable_TableOptions(t *testing.T) {
+	useNarrowEllipsis(t)
+	queries := []string{"a", "b"}
+	tests := []struct {
+		name  string
+		value string
+		opts  TableOptions
+		want  [][]string
+	}{
+		{
+			name:  "no exponent and max width",
+			value: "1e+07",
+			opts:  TableOptions{TimestampFormat: timestampFormatEpoch, NoExponent: true, MaxWidth: 5},
+			want: [][]string{
+				{"1719292597.171", "a", "up", "node…", "10000000"},
+				{"1719292597.171", "b", "", "", "0"},
+			},
+		},
+		{
+			name:  "bool markers",
+			value: "1",
+			opts:  TableOptions{TimestampFormat: timestampFormatEpoch, BoolMarkers: true},
+			want: [][]string{
+				{"1719292597.171", "a", "up", "node-exporter:9100", boolMarkerTrue},
+				{"1719292597.171", "b", "", "", boolMarkerFalse},
+			},
+		},
+		{
+			name:  "unit",
+			value: "2048",
+			opts:  TableOptions{TimestampFormat: timestampFormatEpoch, Units: map[string]string{"up": unitBytes}},
+			want: [][]string{
+				{"1719292597.171", "a", "up", "node-exporter:9100", "2 KiB"},
+				{"1719292597.171", "b", "", "", "0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resps := []*promql.QueryResponse{
+				{Data: promql.Data{ResultType: "vector", Result: promql.ResultVector{
+					{Metric: map[string]string{"__name__": "up", "instance": "node-exporter:9100"}, Point: []any{1719292597.171, tt.value}},
+				}}},
+				{Data: promql.Data{ResultType: "scalar", Result: promql.ResultScalar{1719292597.171, "0"}}},
+			}
+			table, err := buildUnionTable(queries, resps, &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for _, row := range table.Rows {
+				got = append(got, row.Columns)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildUnionTable() rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
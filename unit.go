@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units of the metrics set by `\unit`, which are shown in the value column with the humanized values.
+const (
+	unitBytes   = "bytes"
+	unitSeconds = "seconds"
+	unitPercent = "percent"
+	unitCount   = "count"
+	// unitDuration is the alias of unitSeconds.
+	unitDuration = "duration"
+
+	// durationMetricSuffix is the suffix of the metrics in seconds by the naming convention, which are rendered
+	// as durations with -duration-metrics.
+	durationMetricSuffix = "_seconds"
+)
+
+// unitFormatters format the sample values in each unit. False is returned if the value can't be formatted,
+// such as NaN, and then the value is shown as-is.
+var unitFormatters = map[string]func(float64) (string, bool){
+	unitBytes:    formatBytes,
+	unitSeconds:  humanizeSeconds,
+	unitDuration: humanizeSeconds,
+	unitPercent:  formatPercent,
+	unitCount:    formatCount,
+}
+
+// runUnitCommand runs `\unit <metric> <unit>`, which renders the values of the metric in the unit.
+func (c *CLI) runUnitCommand(args string) error {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 0:
+		if len(c.tableOptions.Units) == 0 {
+			fmt.Fprintf(c.out, "No units\n\n")
+			return nil
+		}
+		metrics := make([]string, 0, len(c.tableOptions.Units))
+		for metric := range c.tableOptions.Units {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+		for _, metric := range metrics {
+			fmt.Fprintf(c.out, "%s: %s\n", metric, c.tableOptions.Units[metric])
+		}
+		fmt.Fprintln(c.out)
+		return nil
+	case 2:
+		metric, unit := fields[0], fields[1]
+		switch unit {
+		case "off":
+			delete(c.tableOptions.Units, metric)
+		default:
+			if _, ok := unitFormatters[unit]; !ok {
+				return fmt.Errorf("unknown unit: %q, expected one of %s, %s, %s or %s", unit, unitBytes, unitSeconds, unitPercent, unitCount)
+			}
+			if c.tableOptions.Units == nil {
+				c.tableOptions.Units = make(map[string]string)
+			}
+			c.tableOptions.Units[metric] = unit
+		}
+		return nil
+	default:
+		return errors.New(`usage: \unit [<metric> <unit>|<metric> off]`)
+	}
+}
+
+// formatValue renders the sample value in the unit of the metric, if any.
+func (o *TableOptions) formatValue(metric map[string]string, value string) string {
+	name := metric["__name__"]
+	unit, ok := o.Units[name]
+	if !ok && o.DurationMetrics && strings.HasSuffix(name, durationMetricSuffix) {
+		unit = unitSeconds
+	}
+	v, ok := parseSampleValue(value)
+	if !ok {
+		return value
+	}
+	if format, ok := unitFormatters[unit]; ok {
+		if formatted, ok := format(v); ok {
+			return formatted
+		}
+	}
+	if o.NoExponent {
+		return plainDecimal(value, v)
+	}
+	return value
+}
+
+// maxPlainDecimalLen is the maximum length of the plain decimals written by plainDecimal. Values needing more,
+// such as 1e+300, are mostly zeros, which are harder to read than the scientific notation.
+const maxPlainDecimalLen = 32
+
+// plainDecimal writes the value in the scientific notation such as 1.5e+07 in plain decimals such as 15000000.
+func plainDecimal(value string, v float64) string {
+	if !strings.ContainsAny(value, "eE") || math.IsNaN(v) || math.IsInf(v, 0) {
+		return value
+	}
+	if plain := strconv.FormatFloat(v, 'f', -1, 64); len(plain) <= maxPlainDecimalLen {
+		return plain
+	}
+	return value
+}
+
+// humanizeSeconds formats the seconds as a duration such as 1h2m3s or 3d4h.
+func humanizeSeconds(seconds float64) (string, bool) {
+	if math.IsNaN(seconds) || math.Abs(seconds) >= float64(math.MaxInt64)/float64(time.Second) {
+		return "", false
+	}
+	d := time.Duration(seconds * float64(time.Second))
+	var sign string
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	switch {
+	case d >= time.Minute:
+		d = d.Round(time.Second)
+	case d >= time.Second:
+		d = d.Round(time.Millisecond)
+	}
+
+	const day = 24 * time.Hour
+	var days string
+	if d >= day {
+		days = fmt.Sprintf("%dd", d/day)
+		d %= day
+		if d == 0 {
+			return sign + days, true
+		}
+	}
+	// Drop the zero units at the end, such as 1h0m0s to 1h.
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return sign + days + s, true
+}
+
+var (
+	byteUnits  = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	countUnits = []string{"", "k", "M", "G", "T", "P", "E"}
+)
+
+// formatBytes formats the bytes in the binary units such as 1.5 GiB.
+func formatBytes(v float64) (string, bool) {
+	return formatScaled(v, 1024, byteUnits, " ")
+}
+
+// formatCount formats the count in the SI units such as 1.2k.
+func formatCount(v float64) (string, bool) {
+	return formatScaled(v, 1000, countUnits, "")
+}
+
+// formatPercent formats the value in 0-100 with the percent sign, such as 42.5%.
+func formatPercent(v float64) (string, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", false
+	}
+	return formatFloat(roundTo(v, 2)) + "%", true
+}
+
+// formatScaled divides the value by the base until it's less than the base, and formats it with the unit
+// rounded to two decimal places.
+func formatScaled(v, base float64, units []string, sep string) (string, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", false
+	}
+	i := 0
+	for math.Abs(v) >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	// Rounding may carry over to the next unit, such as 1023.999 B to 1024 B.
+	v = roundTo(v, 2)
+	if math.Abs(v) >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	if units[i] == "" {
+		return formatFloat(v), true
+	}
+	return formatFloat(v) + sep + units[i], true
+}
+
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// parseSampleValue parses the sample value, including NaN, +Inf and -Inf. False is returned if it's not a number.
+func parseSampleValue(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// compareSampleValues returns a negative number if a is ordered before b, a positive number if after, or zero.
+// The values are ordered as -Inf, the finite numbers, +Inf, and NaN last, since NaN isn't comparable to anything.
+func compareSampleValues(a, b float64) int {
+	switch aNaN, bNaN := math.IsNaN(a), math.IsNaN(b); {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return 1
+	case bNaN:
+		return -1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// singleValue returns the value of the scalar or the vector with one series as written by the server, for -value-only.
+func singleValue(resp *promql.QueryResponse) (string, error) {
+	var point []any
+	switch result := resp.Data.Result.(type) {
+	case promql.ResultScalar:
+		point = result
+	case promql.ResultVector:
+		if len(result) != 1 {
+			return "", fmt.Errorf("expected a single value, but the vector result has %d series", len(result))
+		}
+		point = result[0].Point
+	default:
+		return "", fmt.Errorf("expected a single value, but the result is %s", resp.Data.ResultType)
+	}
+	if len(point) != 2 {
+		return "", fmt.Errorf("malformed sample: %v", point)
+	}
+	value, ok := point[1].(string)
+	if !ok {
+		return "", fmt.Errorf("malformed sample value: %v", point[1])
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+func TestSingleValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *promql.QueryResponse
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "scalar",
+			resp: &promql.QueryResponse{Data: promql.Data{ResultType: "scalar", Result: promql.ResultScalar{1719292597.171, "1e+07"}}},
+			want: "1e+07",
+		},
+		{
+			name: "vector with one series",
+			resp: &promql.QueryResponse{Data: promql.Data{ResultType: "vector", Result: promql.ResultVector{
+				{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.171, "NaN"}},
+			}}},
+			want: "NaN",
+		},
+		{
+			name: "vector with two series",
+			resp: &promql.QueryResponse{Data: promql.Data{ResultType: "vector", Result: promql.ResultVector{
+				{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.171, "1"}},
+				{Metric: map[string]string{"job": "b"}, Point: []any{1719292597.171, "2"}},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:    "matrix",
+			resp:    &promql.QueryResponse{Data: promql.Data{ResultType: "matrix", Result: promql.ResultMatrix{}}},
+			wantErr: true,
+		},
+		{
+			name:    "malformed scalar",
+			resp:    &promql.QueryResponse{Data: promql.Data{ResultType: "scalar", Result: promql.ResultScalar{1719292597.171}}},
+			wantErr: true,
+		},
+		{
+			name: "value not a string",
+			resp: &promql.QueryResponse{Data: promql.Data{ResultType: "vector", Result: promql.ResultVector{
+				{Metric: map[string]string{"job": "a"}, Point: []any{1719292597.171, 1.0}},
+			}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := singleValue(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("singleValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("singleValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSampleValue(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   float64
+		wantOK bool
+	}{
+		{s: "1", want: 1, wantOK: true},
+		{s: "-0.5", want: -0.5, wantOK: true},
+		{s: "1e+07", want: 1e7, wantOK: true},
+		{s: "+Inf", want: math.Inf(1), wantOK: true},
+		{s: "-Inf", want: math.Inf(-1), wantOK: true},
+		{s: "NaN", want: math.NaN(), wantOK: true},
+		{s: ""},
+		{s: "prometheus"},
+		{s: "1 KiB"},
+	}
+	for _, tt := range tests {
+		got, ok := parseSampleValue(tt.s)
+		// NaN isn't equal to itself, so it's compared by math.IsNaN.
+		if ok != tt.wantOK || (math.IsNaN(tt.want) != math.IsNaN(got)) || (!math.IsNaN(tt.want) && got != tt.want) {
+			t.Errorf("parseSampleValue(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCompareSampleValues(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	tests := []struct {
+		a, b float64
+		want int
+	}{
+		{a: 1, b: 2, want: -1},
+		{a: 2, b: 1, want: 1},
+		{a: 1, b: 1, want: 0},
+		{a: -inf, b: -1e308, want: -1},
+		{a: 1e308, b: inf, want: -1},
+		{a: inf, b: inf, want: 0},
+		{a: -inf, b: -inf, want: 0},
+		{a: inf, b: nan, want: -1},
+		{a: nan, b: -inf, want: 1},
+		{a: nan, b: 0, want: 1},
+		{a: nan, b: nan, want: 0},
+	}
+	for _, tt := range tests {
+		if got := compareSampleValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareSampleValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// The order must be total even with NaN, so that sorting is deterministic.
+	values := []float64{nan, 3, inf, -inf, nan, -1, 0}
+	sort.SliceStable(values, func(i, j int) bool { return compareSampleValues(values[i], values[j]) < 0 })
+	want := []string{"-Inf", "-1", "0", "3", "+Inf", "NaN", "NaN"}
+	for i, v := range values {
+		if got := strconv.FormatFloat(v, 'g', -1, 64); got != want[i] {
+			t.Errorf("sorted values = %v, want %v", values, want)
+			break
+		}
+	}
+}
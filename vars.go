@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runVarCommand runs `\var <name>=<value>`, which defines the variable substituted for `$name` or `${name}` in queries.
+func (c *CLI) runVarCommand(args string) error {
+	name, value, ok := strings.Cut(args, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !isVariableName(name) {
+		return errors.New(`usage: \var <name>=<value>`)
+	}
+	if c.vars == nil {
+		c.vars = make(map[string]string)
+	}
+	c.vars[name] = strings.TrimSpace(value)
+	return nil
+}
+
+// runVarsCommand runs `\vars`, which lists the variables. `\vars clear` removes all of them.
+func (c *CLI) runVarsCommand(args string) error {
+	switch args {
+	case "":
+		if len(c.vars) == 0 {
+			fmt.Fprintf(c.out, "No variables\n\n")
+			return nil
+		}
+		names := make([]string, 0, len(c.vars))
+		for name := range c.vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		table := promql.Table{Header: []string{"name", "value"}}
+		for _, name := range names {
+			table.Rows = append(table.Rows, promql.Row{Columns: []string{name, c.vars[name]}})
+		}
+		c.printTable(&table, "")
+		return nil
+	case "clear":
+		c.vars = nil
+		return nil
+	default:
+		return errors.New(`usage: \vars [clear]`)
+	}
+}
+
+// expandVariables substitutes the variables for `$name` and `${name}`, and `$$` for a literal `$`.
+func expandVariables(query string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		if query[i] != '$' || i+1 == len(query) {
+			b.WriteByte(query[i])
+			continue
+		}
+
+		var name string
+		var end int
+		switch rest := query[i+1:]; {
+		case rest[0] == '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case rest[0] == '{':
+			closing := strings.IndexByte(rest, '}')
+			if closing < 0 || !isVariableName(rest[1:closing]) {
+				b.WriteByte('$')
+				continue
+			}
+			name, end = rest[1:closing], i+1+closing
+		default:
+			n := 0
+			for n < len(rest) && (isIdentifierChar(rune(rest[n])) && rest[n] != ':') {
+				n++
+			}
+			if n == 0 || !isVariableName(rest[:n]) {
+				b.WriteByte('$')
+				continue
+			}
+			name, end = rest[:n], i+n
+		}
+
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("undefined variable: $%s (define it by \\var %s=<value>, or write $$ for a literal $)", name, name)
+		}
+		b.WriteString(value)
+		i = end
+	}
+	return b.String(), nil
+}
+
+// isVariableName returns true if the name starts with a letter or an underscore followed by letters, digits, or underscores.
+func isVariableName(name string) bool {
+	if name == "" || isDigit(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !isIdentifierChar(r) || r == ':' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/yfuruyama/promql-cli/pkg/promql"
+)
+
+// runWatchDiffCommand runs `\watch-diff [-t <threshold>] <interval> <query>`, which runs the query every interval
+// and shows only the series whose value changed.
+func (c *CLI) runWatchDiffCommand(ctx context.Context, args string) error {
+	const usage = `usage: \watch-diff [-t <threshold>] <interval> <query>`
+	threshold, args, err := parseThresholdOption(args, "threshold", 0)
+	if err != nil {
+		return err
+	}
+	interval, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if interval == "" || query == "" {
+		return errors.New(usage)
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid interval: %q, expected a duration such as 10s", interval)
+	}
+
+	opts := c.queryOptions()
+	opts.Time = time.Time{}
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	var previous map[string]string
+	for {
+		resp, _, err := c.queryWithOptions(ctx, query, opts)
+		if ctx.Err() != nil {
+			fmt.Fprintf(c.out, "Stopped\n\n")
+			return nil
+		}
+		if err != nil {
+			c.PrintInteractiveError(err)
+		} else {
+			values, err := seriesValues(resp)
+			if err != nil {
+				return err
+			}
+			now := time.Now().Format(time.TimeOnly)
+			if previous == nil {
+				fmt.Fprintf(c.out, "%s %d series, watching every %s until Ctrl-C\n\n", now, len(values), d)
+			} else {
+				table, unchanged := buildChangeTable(previous, values, threshold)
+				c.printTable(table, fmt.Sprintf("%s %d changed, %d unchanged", now, len(table.Rows), unchanged))
+			}
+			previous = values
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(c.out, "Stopped\n\n")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildChangeTable builds the table of the series whose values changed by more than the threshold, or which appeared
+// or disappeared, and returns it with the number of the unchanged series.
+func buildChangeTable(previous, current map[string]string, threshold float64) (*promql.Table, int) {
+	table := promql.Table{Header: []string{"labels", "previous", "current", "delta"}}
+	var unchanged int
+	for _, pair := range joinSeries(previous, current) {
+		before, after := pair.A, pair.B
+		var delta string
+		switch {
+		case !pair.InB:
+			after, delta = "-", "gone"
+		case !pair.InA:
+			before, delta = "-", "new"
+		case !valueChanged(before, after, threshold):
+			unchanged++
+			continue
+		default:
+			delta = formatDelta(before, after)
+		}
+		table.Rows = append(table.Rows, promql.Row{Columns: []string{pair.Fingerprint, before, after, delta}})
+	}
+	return &table, unchanged
+}
+
+// valueChanged returns true if the value changed by more than the threshold. NaN is unchanged only from NaN.
+func valueChanged(before, after string, threshold float64) bool {
+	b, okBefore := parseSampleValue(before)
+	a, okAfter := parseSampleValue(after)
+	if !okBefore || !okAfter {
+		return before != after
+	}
+	switch {
+	case math.IsNaN(b) || math.IsNaN(a):
+		return math.IsNaN(b) != math.IsNaN(a)
+	case math.IsInf(b, 0) || math.IsInf(a, 0):
+		return a != b
+	}
+	return math.Abs(a-b) > threshold
+}
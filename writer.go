@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// lockedWriter serializes writes to the underlying writer. Writers sharing the same mutex are serialized together.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// newLockedWriters returns the writers serialized by one mutex.
+func newLockedWriters(writers ...io.Writer) []io.Writer {
+	mu := &sync.Mutex{}
+	locked := make([]io.Writer, len(writers))
+	for i, w := range writers {
+		locked[i] = &lockedWriter{mu: mu, w: w}
+	}
+	return locked
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// Unwrap returns the underlying writer.
+func (w *lockedWriter) Unwrap() io.Writer {
+	return w.w
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestLockedWriters writes to the writers sharing one buffer concurrently, like the spinner and the error messages
+// writing to the output and the error output of the terminal. Run with -race to detect unserialized writes.
+func TestLockedWriters(t *testing.T) {
+	const goroutines, writes = 8, 100
+	var buf bytes.Buffer
+	writers := newLockedWriters(&buf, &buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := writers[i%len(writers)]
+			for j := 0; j < writes; j++ {
+				fmt.Fprintf(w, "writer %d: line %d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*writes {
+		t.Fatalf("got %d lines, want %d", len(lines), goroutines*writes)
+	}
+	next := make(map[int]int)
+	for _, line := range lines {
+		var i, j int
+		if _, err := fmt.Sscanf(line, "writer %d: line %d", &i, &j); err != nil {
+			t.Fatalf("interleaved line %q: %v", line, err)
+		}
+		if j != next[i] {
+			t.Fatalf("writer %d wrote line %d, want line %d", i, j, next[i])
+		}
+		next[i]++
+	}
+}
+
+func TestLockedWriters_Unwrap(t *testing.T) {
+	var buf bytes.Buffer
+	w := newLockedWriters(&buf)[0]
+	if got := w.(*lockedWriter).Unwrap(); got != &buf {
+		t.Errorf("Unwrap() = %v, want the underlying writer", got)
+	}
+}